@@ -0,0 +1,97 @@
+// Command stalk-gen generates typed bindings of the flags for the workflow declared by specification
+// It is intended to be used with 'go generate', e.g.:
+//
+//	//go:generate stalk-gen generate --spec app.yaml --package cli --output cli_gen.go
+//
+// Generated bindings detect changed declaration only at runtime, when `Bind` is called,
+// 'check' command fails if generated code is outdated, so it can be used in the build to detect it earlier:
+//
+//	stalk-gen check --spec app.yaml --package cli --output cli_gen.go
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+	"github.com/pavelmemory/stalk/gen"
+	"github.com/pavelmemory/stalk/spec"
+)
+
+func main() {
+	stalk.Main(stalk.New().
+		WithName("stalk-gen").
+		WithDescription("generates typed bindings of the flags for the workflow declared by specification").
+		WithCommands(
+			command.New("generate").
+				WithDescription("writes Go code with a struct and 'Bind' method for each command").
+				WithFlags(
+					flag.String("spec").WithShortcut('s').Required(true).WithDescription("JSON or YAML specification of the workflow"),
+					flag.StringWithDefault("package", "cli").WithShortcut('p').WithDescription("package of the generated code"),
+					flag.String("output").WithShortcut('o').WithDescription("file to write generated code into, output stream is used if not set"),
+				).
+				WithExample("generate bindings for 'app.yaml'", "generate --spec app.yaml --package cli --output cli_gen.go").
+				WithAction(generate),
+			command.New("check").
+				WithDescription("fails if generated code is different from the code generated for the specification").
+				WithFlags(
+					flag.String("spec").WithShortcut('s').Required(true).WithDescription("JSON or YAML specification of the workflow"),
+					flag.StringWithDefault("package", "cli").WithShortcut('p').WithDescription("package of the generated code"),
+					flag.String("output").WithShortcut('o').Required(true).WithDescription("file with generated code"),
+				).
+				WithExample("check bindings generated for 'app.yaml'", "check --spec app.yaml --package cli --output cli_gen.go").
+				WithAction(check),
+		))
+}
+
+func generate(ctx common.Runtime) error {
+	generator, err := newGenerator(ctx)
+	if err != nil {
+		return err
+	}
+	code, err := generator.Generate()
+	if err != nil {
+		return err
+	}
+	if output := ctx.StringFlag("output"); output != "" {
+		return ioutil.WriteFile(output, code, 0644)
+	}
+	_, err = ctx.Stdout().Write(code)
+	return err
+}
+
+func check(ctx common.Runtime) error {
+	generator, err := newGenerator(ctx)
+	if err != nil {
+		return err
+	}
+	return generator.Check(ctx.StringFlag("output"))
+}
+
+// newGenerator creates generator for the specification and package provided by flags
+func newGenerator(ctx common.Runtime) (gen.Generator, error) {
+	path := ctx.StringFlag("spec")
+	s, err := spec.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	// actions are not executed, so each of them is replaced with a stub
+	w, err := spec.Build(s, stubs(make(spec.Registry), s.Commands))
+	if err != nil {
+		return nil, err
+	}
+	return gen.New(w).WithPackage(ctx.StringFlag("package")).WithSource(filepath.Base(path)), nil
+}
+
+func stubs(actions spec.Registry, commands []spec.Command) spec.Registry {
+	for _, cmd := range commands {
+		if cmd.Action != "" {
+			actions[cmd.Action] = func(ctx common.Runtime) error { return nil }
+		}
+		stubs(actions, cmd.Commands)
+	}
+	return actions
+}
@@ -2,8 +2,10 @@ package command
 
 import (
 	"strings"
+	"time"
 
 	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
 )
 
 var (
@@ -54,6 +56,15 @@ type declaration struct {
 	onError             func(ctx common.Runtime, err error)
 	stringer            func(declaration common.CommandDeclaration) string
 	description         string
+	examples            []common.Example
+	middlewares         []common.Middleware
+	confirmation        string
+	confirmationFlag    common.Flag
+	skipFlag            common.Flag
+	confirmationErr     error
+	timeout             time.Duration
+	hidden              bool
+	deprecation         *common.Deprecation
 	declErrs            []error
 }
 
@@ -64,11 +75,38 @@ func (c *declaration) GetName() string {
 func (c *declaration) WithFlags(flags ...common.Flag) common.CommandDeclaration {
 	c.declErrs = append(c.declErrs, common.ValidateFlagDeclarations(flags)...)
 	c.declaredFlags = flags
+	c.updateConfirmationFlag()
 	return c
 }
 
 func (c *declaration) GetDeclaredFlags() []common.Flag {
-	return c.declaredFlags
+	if c.skipFlag == nil {
+		return c.declaredFlags
+	}
+	return append(append([]common.Flag(nil), c.declaredFlags...), c.skipFlag)
+}
+
+// updateConfirmationFlag selects flag that skips confirmation after change of declared flags or of confirmation
+// `skipFlag` is added to declared flags, it is 'nil' if declared flag with the same name is used instead
+// Declared flag with the same name must be a signal or boolean flag, so its value decides whether confirmation is skipped
+func (c *declaration) updateConfirmationFlag() {
+	c.skipFlag, c.confirmationErr = nil, nil
+	if c.confirmationFlag == nil {
+		return
+	}
+	for _, f := range c.declaredFlags {
+		if f.GetName() != common.ConfirmationFlagName {
+			continue
+		}
+		if typed, ok := f.(common.Typed); !f.IsDeclaredSignal() && (!ok || typed.GetDeclaredTypeName() != "BOOL") {
+			c.confirmationErr = common.FlagNameInvalidError("flag '--" + common.ConfirmationFlagName + "' of command '" + c.name + "' skips confirmation, it must be a signal or boolean flag")
+		}
+		return
+	}
+	c.skipFlag = c.confirmationFlag
+	if common.IsShadowed(c.confirmationFlag, c.declaredFlags) {
+		c.skipFlag = flag.Signal(common.ConfirmationFlagName).WithDescription(c.confirmationFlag.GetDeclaredDescription())
+	}
 }
 
 func (c *declaration) WithSubCommands(commands ...common.CommandDeclaration) common.CommandDeclaration {
@@ -154,6 +192,73 @@ func (c *declaration) GetDeclaredDescription() string {
 	return c.description
 }
 
+func (c *declaration) WithExample(description, commandLine string) common.CommandDeclaration {
+	c.examples = append(c.examples, common.Example{Description: description, CommandLine: commandLine})
+	return c
+}
+
+func (c *declaration) GetDeclaredExamples() []common.Example {
+	return c.examples
+}
+
+func (c *declaration) Use(middlewares ...common.Middleware) common.CommandDeclaration {
+	for _, middleware := range middlewares {
+		if middleware == nil {
+			c.declErrs = append(c.declErrs, common.ActionInvalidError("middleware is 'nil': "+c.name))
+		}
+	}
+	c.middlewares = append(c.middlewares, middlewares...)
+	return c
+}
+
+func (c *declaration) GetDeclaredMiddlewares() []common.Middleware {
+	return c.middlewares
+}
+
+func (c *declaration) WithConfirmation(message string) common.CommandDeclaration {
+	if strings.TrimSpace(message) == "" {
+		c.declErrs = append(c.declErrs, common.ActionInvalidError("confirmation message is empty: "+c.name))
+	}
+	c.confirmation = message
+	c.confirmationFlag = flag.Signal(common.ConfirmationFlagName).WithShortcut('y').WithDescription("skip confirmation")
+	c.updateConfirmationFlag()
+	return c
+}
+
+func (c *declaration) GetDeclaredConfirmation() string {
+	return c.confirmation
+}
+
+func (c *declaration) WithTimeout(timeout time.Duration) common.CommandDeclaration {
+	c.timeout = timeout
+	return c
+}
+
+func (c *declaration) GetDeclaredTimeout() time.Duration {
+	return c.timeout
+}
+
+func (c *declaration) Hidden() common.CommandDeclaration {
+	c.hidden = true
+	return c
+}
+
+func (c *declaration) IsDeclaredHidden() bool {
+	return c.hidden
+}
+
+func (c *declaration) Deprecated(message, replacement string) common.CommandDeclaration {
+	c.deprecation = &common.Deprecation{Message: message, Replacement: replacement}
+	return c
+}
+
+func (c *declaration) GetDeclaredDeprecation() *common.Deprecation {
+	return c.deprecation
+}
+
 func (c *declaration) GetDeclarationErrors() []error {
+	if c.confirmationErr != nil {
+		return append(append([]error(nil), c.declErrs...), c.confirmationErr)
+	}
 	return c.declErrs
 }
@@ -0,0 +1,332 @@
+package command
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+// Names of the struct field tags used by `FromStruct`
+const (
+	// TagFlag declares flag, its value is a name of the flag, kebab-cased name of the field is used if it is empty
+	TagFlag = "flag"
+	// TagShort sets shortcut of the flag
+	TagShort = "short"
+	// TagDefault sets default value of the flag
+	TagDefault = "default"
+	// TagRequired marks flag as required if it is 'true'
+	TagRequired = "required"
+	// TagEnv sets name of the environment variable used as a value of the flag, see `common.Flag.WithEnv`
+	TagEnv = "env"
+	// TagDescription sets description of the flag or of the command
+	TagDescription = "description"
+	// TagHidden hides flag or command if it is 'true'
+	TagHidden = "hidden"
+	// TagSensitive marks value of the flag as a secret if it is 'true'
+	TagSensitive = "sensitive"
+	// TagArgs marks field of '[]string' type that receives arguments of the command
+	TagArgs = "args"
+	// TagCommand declares child command from the field of struct type, its value is a name of the command
+	TagCommand = "command"
+)
+
+// Runner can be implemented by structs passed to `FromStruct` to become an action of the command
+type Runner interface {
+	// Run is executed after fields of the struct are filled with values of flags and arguments
+	Run(ctx common.Runtime) error
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FromStruct creates command with `name` which flags are declared by tagged fields of the struct pointed by `options`
+// Fields of 'string', 'bool', 'int*', 'float*' and 'time.Duration' types tagged with `TagFlag` become flags,
+// 'bool' fields without default value and not required become signal flags
+// Field of '[]string' type tagged with `TagArgs` receives arguments of the command
+// Fields of struct type (or pointer to it) tagged with `TagCommand` become child commands declared the same way
+// Before execution of the command fields of its struct are filled with values of flags and arguments,
+// and `Run` method is executed if struct implements `Runner`
+// Action set with `WithAction` replaces filling of the fields
+func FromStruct(name string, options interface{}) common.CommandDeclaration {
+	value := reflect.ValueOf(options)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		decl := New(name).(*declaration)
+		decl.declErrs = append(decl.declErrs, common.StructInvalidError("pointer to struct expected for command '"+name+"': "+value.Kind().String()))
+		return decl
+	}
+	return fromStruct(name, value.Elem())
+}
+
+// fromStruct creates command from addressable struct value `v`
+func fromStruct(name string, v reflect.Value) common.CommandDeclaration {
+	decl := New(name).(*declaration)
+	b := &binding{value: v, flags: make(map[int]common.Flag), args: -1}
+
+	var flags []common.Flag
+	var subCommands []common.CommandDeclaration
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if flagName, found := field.Tag.Lookup(TagFlag); found {
+			f, err := structFlag(field, flagName)
+			if err != nil {
+				decl.declErrs = append(decl.declErrs, common.StructInvalidError("field '"+field.Name+"' of command '"+name+"': "+err.Error()))
+				continue
+			}
+			b.flags[i] = f
+			flags = append(flags, f)
+			continue
+		}
+
+		if _, found := field.Tag.Lookup(TagArgs); found {
+			if field.Type != reflect.TypeOf([]string(nil)) {
+				decl.declErrs = append(decl.declErrs, common.StructInvalidError("field '"+field.Name+"' of command '"+name+"': arguments require '[]string' type"))
+				continue
+			}
+			b.args = i
+			continue
+		}
+
+		if cmdName, found := field.Tag.Lookup(TagCommand); found {
+			subCmd, err := structCommand(field, cmdName, v.Field(i))
+			if err != nil {
+				decl.declErrs = append(decl.declErrs, common.StructInvalidError("field '"+field.Name+"' of command '"+name+"': "+err.Error()))
+				continue
+			}
+			subCommands = append(subCommands, subCmd)
+		}
+	}
+
+	if len(flags) != 0 {
+		decl.WithFlags(flags...)
+	}
+	if len(subCommands) != 0 {
+		decl.WithSubCommands(subCommands...)
+	}
+	decl.WithAction(b.run)
+	return decl
+}
+
+// structCommand creates child command from the field of struct or pointer to struct type
+func structCommand(field reflect.StructField, name string, v reflect.Value) (common.CommandDeclaration, error) {
+	if name == "" {
+		name = kebabCase(field.Name)
+	}
+	switch {
+	case v.Kind() == reflect.Struct:
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	default:
+		return nil, errors.New("command requires struct type: " + field.Type.String())
+	}
+
+	cmd := fromStruct(name, v)
+	if description := field.Tag.Get(TagDescription); description != "" {
+		cmd.WithDescription(description)
+	}
+	hidden, err := boolTag(field, TagHidden)
+	if err != nil {
+		return nil, err
+	}
+	if hidden {
+		cmd.Hidden()
+	}
+	return cmd, nil
+}
+
+// structFlag creates flag of the type matching to the type of the field
+func structFlag(field reflect.StructField, name string) (common.Flag, error) {
+	if name == "" {
+		name = kebabCase(field.Name)
+	}
+	defaultValue, hasDefault := field.Tag.Lookup(TagDefault)
+	required, err := boolTag(field, TagRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	var f common.Flag
+	switch kind := field.Type.Kind(); {
+	case field.Type == durationType:
+		f = flag.Duration(name)
+		if hasDefault {
+			value, err := time.ParseDuration(defaultValue)
+			if err != nil {
+				return nil, errors.New("default value: " + err.Error())
+			}
+			f = flag.DurationWithDefault(name, value)
+		}
+	case kind == reflect.String:
+		f = flag.String(name)
+		if hasDefault {
+			f = flag.StringWithDefault(name, defaultValue)
+		}
+	case kind == reflect.Bool:
+		f = flag.Signal(name)
+		if required {
+			f = flag.Bool(name)
+		}
+		if hasDefault {
+			value, err := strconv.ParseBool(defaultValue)
+			if err != nil {
+				return nil, errors.New("default value: " + err.Error())
+			}
+			f = flag.BoolWithDefault(name, value)
+		}
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		f = flag.Int(name)
+		if hasDefault {
+			value, err := strconv.ParseInt(defaultValue, 10, field.Type.Bits())
+			if err != nil {
+				return nil, errors.New("default value: " + err.Error())
+			}
+			f = flag.IntWithDefault(name, value)
+		}
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		f = flag.Float(name)
+		if hasDefault {
+			value, err := strconv.ParseFloat(defaultValue, field.Type.Bits())
+			if err != nil {
+				return nil, errors.New("default value: " + err.Error())
+			}
+			f = flag.FloatWithDefault(name, value)
+		}
+	default:
+		return nil, errors.New("unsupported flag type: " + field.Type.String())
+	}
+
+	if required {
+		f.Required(true)
+	}
+	if short := field.Tag.Get(TagShort); short != "" {
+		shortcut, size := utf8.DecodeRuneInString(short)
+		if size != len(short) {
+			return nil, errors.New("shortcut must be a single character: " + short)
+		}
+		f.WithShortcut(shortcut)
+	}
+	if env := field.Tag.Get(TagEnv); env != "" {
+		f.WithEnv(env)
+	}
+	if description := field.Tag.Get(TagDescription); description != "" {
+		f.WithDescription(description)
+	}
+	hidden, err := boolTag(field, TagHidden)
+	if err != nil {
+		return nil, err
+	}
+	if hidden {
+		f.Hidden()
+	}
+	sensitive, err := boolTag(field, TagSensitive)
+	if err != nil {
+		return nil, err
+	}
+	if sensitive {
+		f.Sensitive()
+	}
+	return f, nil
+}
+
+func boolTag(field reflect.StructField, tag string) (bool, error) {
+	value, found := field.Tag.Lookup(tag)
+	if !found {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("tag '" + tag + "': " + err.Error())
+	}
+	return enabled, nil
+}
+
+// binding fills fields of the struct with values of flags and arguments of the command
+type binding struct {
+	value reflect.Value
+	// flags by index of the field
+	flags map[int]common.Flag
+	// args is an index of the field that receives arguments, -1 if there is no such field
+	args int
+}
+
+func (b *binding) run(ctx common.Runtime) error {
+	found := ctx.CurrentCommand().GetFlags()
+	for index, f := range b.flags {
+		field := b.value.Field(index)
+		_, provided := found[f.GetName()]
+		switch {
+		case f.IsDeclaredSignal():
+			field.SetBool(provided)
+			continue
+		case !provided && !f.HasDefault():
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		if err := setField(field, f); err != nil {
+			return err
+		}
+	}
+	if b.args >= 0 {
+		b.value.Field(b.args).Set(reflect.ValueOf(append([]string(nil), ctx.GetArgs()...)))
+	}
+
+	if runner, ok := b.value.Addr().Interface().(Runner); ok {
+		return runner.Run(ctx)
+	}
+	return nil
+}
+
+// setField sets parsed value of the flag to the field
+func setField(field reflect.Value, f common.Flag) error {
+	switch kind := field.Kind(); {
+	case field.Type() == durationType:
+		field.SetInt(int64(f.(common.ParsedDuration).DurationValue()))
+	case kind == reflect.String:
+		field.SetString(f.(common.ParsedString).StringValue())
+	case kind == reflect.Bool:
+		field.SetBool(f.(common.ParsedBool).BoolValue())
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		value := f.(common.ParsedInt).IntValue()
+		if field.OverflowInt(value) {
+			return common.FlagValueInvalidError("--" + f.GetName() + ": " + common.Redact(f, strconv.FormatInt(value, 10)) + " is out of range of " + field.Type().String())
+		}
+		field.SetInt(value)
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		value := f.(common.ParsedFloat).FloatValue()
+		if field.OverflowFloat(value) {
+			return common.FlagValueInvalidError("--" + f.GetName() + ": " + common.Redact(f, strconv.FormatFloat(value, 'g', -1, 64)) + " is out of range of " + field.Type().String())
+		}
+		field.SetFloat(value)
+	}
+	return nil
+}
+
+// kebabCase converts name of the field into name of the flag or command, e.g. 'DryRun' into 'dry-run'
+func kebabCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// word starts at upper case letter following lower case one or preceding lower case one in abbreviation
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])) {
+				b.WriteRune('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
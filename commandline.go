@@ -0,0 +1,102 @@
+package stalk
+
+import (
+	"github.com/pavelmemory/stalk/common"
+)
+
+// splitCommandLine splits `line` into arguments the same way shell does
+// Arguments are separated by whitespaces, single quotes preserve literal value of all characters,
+// double quotes preserve literal value of all characters except backslash escaped '"' and '\',
+// backslash outside of quotes preserves literal value of the next character
+func splitCommandLine(line string) ([]string, error) {
+	words, err := splitWords(line)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	for _, w := range words {
+		args = append(args, string(w.runes))
+	}
+	return args, nil
+}
+
+// word is an argument of the command line
+// Characters quoted by single quotes or escaped by backslash are marked as literal
+type word struct {
+	runes   []rune
+	literal []bool
+}
+
+func (w *word) add(r rune, literal bool) {
+	w.runes = append(w.runes, r)
+	w.literal = append(w.literal, literal)
+}
+
+// splitWords splits `line` into arguments as `splitCommandLine` does keeping track of literal characters
+func splitWords(line string) ([]word, error) {
+	var words []word
+	var current word
+	inArg := false
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				words = append(words, current)
+				current = word{}
+				inArg = false
+			}
+		case r == '\'':
+			inArg = true
+			end := indexRune(runes, '\'', i+1)
+			if end < 0 {
+				return nil, common.CommandLineSyntaxError("unterminated single quote: " + line)
+			}
+			for _, quoted := range runes[i+1 : end] {
+				current.add(quoted, true)
+			}
+			i = end
+		case r == '"':
+			inArg = true
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '"' {
+					closed = true
+					break
+				}
+				escaped := false
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+					escaped = true
+				}
+				current.add(runes[i], escaped)
+			}
+			if !closed {
+				return nil, common.CommandLineSyntaxError("unterminated double quote: " + line)
+			}
+		case r == '\\':
+			inArg = true
+			if i+1 < len(runes) {
+				i++
+				current.add(runes[i], true)
+			}
+		default:
+			inArg = true
+			current.add(r, false)
+		}
+	}
+	if inArg {
+		words = append(words, current)
+	}
+	return words, nil
+}
+
+func indexRune(runes []rune, r rune, from int) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"time"
 )
 
 // CommandDeclaration is a declaration of command to be used as part of workflow
@@ -25,10 +26,13 @@ type CommandDeclaration interface {
 	// GetDeclaredBefore returns action that executes before main command task or any child command
 	GetDeclaredBefore() func(ctx Runtime) error
 	// WithAfter sets action to be executed after main command task or any child command
+	// It is executed on success with 'nil' `err` and on failure after `OnError` action
+	// After actions of child commands are executed before After action of their parent
 	WithAfter(action func(ctx Runtime, err error)) CommandDeclaration
 	// GetDeclaredAfter returns action that executes after main command task or any child command
 	GetDeclaredAfter() func(ctx Runtime, err error)
 	// WithOnError sets action to be executed if command task return an error
+	// It is executed before `After` action of the same command
 	WithOnError(func(ctx Runtime, err error)) CommandDeclaration
 	// GetDeclaredOnError returns action to be executed if command task return an error
 	GetDeclaredOnError() func(ctx Runtime, err error)
@@ -41,10 +45,81 @@ type CommandDeclaration interface {
 	WithDescription(value string) CommandDeclaration
 	// GetDescription returns description for this command
 	GetDeclaredDescription() string
+	// WithExample adds usage example of this command
+	// `commandLine` is a full command line without application name, e.g. 'aws create --name test'
+	WithExample(description, commandLine string) CommandDeclaration
+	// GetDeclaredExamples returns usage examples of this command
+	GetDeclaredExamples() []Example
+	// Use adds middlewares applied to the main task of this command and of all its child commands
+	// Middlewares of the parent command wrap middlewares of the child command
+	Use(middlewares ...Middleware) CommandDeclaration
+	// GetDeclaredMiddlewares returns middlewares added to this command
+	GetDeclaredMiddlewares() []Middleware
+	// WithConfirmation makes this command ask user for confirmation with `message` before its execution
+	// Flag '--yes' ('-y') is added to the command to skip the question, it is required if user can't be asked
+	// Declared flag with the same name is used instead of it, shortcut is not added if it is already declared
+	// If user declines or can't be asked execution ends with `ErrorAborted` error
+	WithConfirmation(message string) CommandDeclaration
+	// GetDeclaredConfirmation returns message used to ask user for confirmation, empty if confirmation is not required
+	GetDeclaredConfirmation() string
+	// WithTimeout limits execution time of this command and its child commands
+	// Actions that don't complete in time are abandoned and command ends with `ErrorTimeout` error
+	// Abandoned action keeps running with a snapshot of the runtime that is not changed by the following hooks
+	WithTimeout(timeout time.Duration) CommandDeclaration
+	// GetDeclaredTimeout returns execution time limit of this command, 0 means no limit
+	GetDeclaredTimeout() time.Duration
+	// Hidden sets this command as hidden: it is accepted by parser but omitted from help and documentation
+	Hidden() CommandDeclaration
+	// IsDeclaredHidden returns `true` if this command is hidden
+	IsDeclaredHidden() bool
+	// Deprecated sets this command as deprecated, a warning is emitted each time it is used
+	// `replacement` is a command that should be used instead, it is used in warning message only
+	Deprecated(message, replacement string) CommandDeclaration
+	// GetDeclaredDeprecation returns deprecation details or 'nil' if command is not deprecated
+	GetDeclaredDeprecation() *Deprecation
 	// GetDeclarationErrors returns errors found in declaration of command
 	GetDeclarationErrors() []error
 }
 
+// Deprecation describes why flag or command is deprecated and what should be used instead
+type Deprecation struct {
+	// Message explains the reason of deprecation
+	Message string `json:"message,omitempty"`
+	// Replacement is a name of the flag or command that should be used instead, may be empty
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Warning returns message about usage of deprecated `subject`
+func (d Deprecation) Warning(subject string) string {
+	warning := subject + " is deprecated"
+	if d.Message != "" {
+		warning += ": " + d.Message
+	}
+	if d.Replacement != "" {
+		warning += ", use '" + d.Replacement + "' instead"
+	}
+	return warning
+}
+
+// VisibleCommands returns commands that are not hidden
+func VisibleCommands(commands []CommandDeclaration) []CommandDeclaration {
+	var visible []CommandDeclaration
+	for _, cmd := range commands {
+		if !cmd.IsDeclaredHidden() {
+			visible = append(visible, cmd)
+		}
+	}
+	return visible
+}
+
+// Example is a usage example of the command shown in help and documentation
+type Example struct {
+	// Description explains what example does
+	Description string `json:"description,omitempty"`
+	// CommandLine is a full command line without application name
+	CommandLine string `json:"commandLine"`
+}
+
 // Parsed represents command parsed from provided arguments list with supported flags and sub-commands
 type ParsedCommand interface {
 	CommandDeclaration
@@ -60,17 +135,28 @@ type ParsedCommand interface {
 
 // validates provided slice of flags and returns founded errors
 func ValidateFlagDeclarations(flags []Flag) []error {
-	var emptyShortcut rune
 	var errs []error
+	ValidateEachFlagDeclaration(flags, func(index int, err error) {
+		errs = append(errs, err)
+	})
+	return errs
+}
+
+// ValidateEachFlagDeclaration validates provided slice of flags as `ValidateFlagDeclarations` does
+// and calls `report` for each founded error with index of the flag caused it
+func ValidateEachFlagDeclaration(flags []Flag, report func(index int, err error)) {
+	var emptyShortcut rune
 	expectedFlagsByName := make(map[string]Flag)
 	expectedFlagsByShortcut := make(map[rune]Flag)
 
-	for _, flag := range flags {
-		errs = append(errs, flag.GetDeclarationErrors()...)
+	for index, flag := range flags {
+		for _, err := range flag.GetDeclarationErrors() {
+			report(index, err)
+		}
 
 		flagName := flag.GetName()
 		if _, found := expectedFlagsByName[flagName]; found {
-			errs = append(errs, FlagNameNotUniqueError(flag.String()))
+			report(index, FlagNameNotUniqueError(flag.String()))
 		}
 		expectedFlagsByName[flagName] = flag
 
@@ -79,31 +165,42 @@ func ValidateFlagDeclarations(flags []Flag) []error {
 			continue
 		}
 		if _, found := expectedFlagsByShortcut[shortcut]; found {
-			errs = append(errs, FlagShortcutNotUniqueError(flag.String()))
+			report(index, FlagShortcutNotUniqueError(flag.String()))
 		}
 		if foundFlag, found := expectedFlagsByName[string(shortcut)]; found && foundFlag != flag {
-			errs = append(errs, FlagShortcutNameSameError(flag.String()+" and "+foundFlag.String()))
+			report(index, FlagShortcutNameSameError(flag.String()+" and "+foundFlag.String()))
 		}
 		expectedFlagsByShortcut[shortcut] = flag
 	}
-	return errs
 }
 
 // ValidateCommandDeclarations validates provided slice of commands and returns founded errors
 func ValidateCommandDeclarations(commands []CommandDeclaration) []error {
 	var errs []error
+	ValidateEachCommandDeclaration(commands, func(index int, err error, declared bool) {
+		errs = append(errs, err)
+	})
+	return errs
+}
+
+// ValidateEachCommandDeclaration validates provided slice of commands as `ValidateCommandDeclarations` does
+// and calls `report` for each founded error with index of the command caused it
+// `declared` is `true` for errors returned by `GetDeclarationErrors` of the command,
+// they include errors of its flags and child commands
+func ValidateEachCommandDeclaration(commands []CommandDeclaration, report func(index int, err error, declared bool)) {
 	cmdByName := make(map[string]CommandDeclaration)
-	for _, cmd := range commands {
+	for index, cmd := range commands {
 		cmdName := cmd.GetName()
 		if _, found := cmdByName[cmdName]; found {
-			errs = append(errs, CommandNameNotUniqueError(cmdName))
+			report(index, CommandNameNotUniqueError(cmdName), false)
+		}
+		for _, err := range cmd.GetDeclarationErrors() {
+			report(index, err, true)
 		}
-		errs = append(errs, cmd.GetDeclarationErrors()...)
 
 		if cmd.GetDeclaredAction() == nil && len(cmd.GetDeclaredSubCommands()) == 0 {
-			errs = append(errs, ActionInvalidError("command '"+cmdName+"' has no action neither sub-commands to execute"))
+			report(index, ActionInvalidError("command '"+cmdName+"' has no action neither sub-commands to execute"), false)
 		}
 		cmdByName[cmdName] = cmd
 	}
-	return errs
 }
@@ -3,6 +3,8 @@ package common
 import (
 	"bytes"
 	"fmt"
+	"runtime/debug"
+	"strconv"
 )
 
 // Error type will be returned if declaration errors were found or some errors will appear on processing of args
@@ -103,6 +105,59 @@ func ActionInvalidError(msg string) Error {
 	return Error{Cause: ErrorActionInvalid, ContextMessage: msg}
 }
 
+// ExampleInvalidError returns Error with corresponding function name ErrorCode and provided msg as ContextMessage
+func ExampleInvalidError(msg string) Error {
+	return Error{Cause: ErrorExampleInvalid, ContextMessage: msg}
+}
+
+// CommandLineSyntaxError returns Error with corresponding function name ErrorCode and provided msg as ContextMessage
+func CommandLineSyntaxError(msg string) Error {
+	return Error{Cause: ErrorCommandLineSyntax, ContextMessage: msg}
+}
+
+// FlagValueInvalidError returns Error with corresponding function name ErrorCode and provided msg as ContextMessage
+func FlagValueInvalidError(msg string) Error {
+	return Error{Cause: ErrorFlagValueInvalid, ContextMessage: msg}
+}
+
+// AbortedError returns Error with corresponding function name ErrorCode and provided msg as ContextMessage
+func AbortedError(msg string) Error {
+	return Error{Cause: ErrorAborted, ContextMessage: msg}
+}
+
+// StructInvalidError returns Error with corresponding function name ErrorCode and provided msg as ContextMessage
+func StructInvalidError(msg string) Error {
+	return Error{Cause: ErrorStructInvalid, ContextMessage: msg}
+}
+
+// BindingMismatchError returns Error with corresponding function name ErrorCode and provided msg as ContextMessage
+func BindingMismatchError(msg string) Error {
+	return Error{Cause: ErrorBindingMismatch, ContextMessage: msg}
+}
+
+// InterruptedError returns Error with corresponding function name ErrorCode and provided msg as ContextMessage
+func InterruptedError(msg string) Error {
+	return Error{Cause: ErrorInterrupted, ContextMessage: msg}
+}
+
+// TimeoutError returns Error with corresponding function name ErrorCode and provided msg as ContextMessage
+func TimeoutError(msg string) Error {
+	return Error{Cause: ErrorTimeout, ContextMessage: msg}
+}
+
+// PanicError returns Error with corresponding function name ErrorCode and panic value with stack trace as ContextMessage
+func PanicError(value interface{}, stack []byte) Error {
+	return Error{Cause: ErrorPanic, ContextMessage: fmt.Sprintf("%v\n%s", value, stack)}
+}
+
+// RecoverPanic recovers from panic and stores it into `err` as Error caused by ErrorPanic
+// It must be deferred directly: `defer common.RecoverPanic(&err)`
+func RecoverPanic(err *error) {
+	if value := recover(); value != nil {
+		*err = PanicError(value, debug.Stack())
+	}
+}
+
 // ErrorCode represents general cases of errors
 type ErrorCode byte
 
@@ -139,6 +194,24 @@ const (
 	ErrorCommandNameNotUnique
 	// ErrorActionInvalid signals that action is not a valid action (usually 'nil' value)
 	ErrorActionInvalid
+	// ErrorExampleInvalid signals that command line of declared command example can't be parsed or leads to another command
+	ErrorExampleInvalid
+	// ErrorCommandLineSyntax signals that command line can't be split into arguments, e.g. it has unterminated quote
+	ErrorCommandLineSyntax
+	// ErrorInterrupted signals that execution was interrupted by the signal
+	ErrorInterrupted
+	// ErrorTimeout signals that execution was not completed in declared time
+	ErrorTimeout
+	// ErrorPanic signals that action panicked, context message contains panic value and stack trace
+	ErrorPanic
+	// ErrorFlagValueInvalid signals that value passed for the flag can't be converted into declared type
+	ErrorFlagValueInvalid
+	// ErrorAborted signals that user declined confirmation of the command or confirmation was not possible
+	ErrorAborted
+	// ErrorStructInvalid signals that struct used to declare command has unsupported field type or invalid tag value
+	ErrorStructInvalid
+	// ErrorBindingMismatch signals that generated binding doesn't match declaration of the command it is used with
+	ErrorBindingMismatch
 )
 
 // String returns string representation for ErrorCode values
@@ -165,10 +238,52 @@ var errorCodeNames = map[ErrorCode]string{
 	ErrorFlagNameInvalid:   "invalid flag name",
 	ErrorFlagNameNotUnique: "flag name is not unique",
 
+	ErrorFlagRequiredAndHasDefault: "required flag has default value",
+	ErrorFlagSignalAndRequired:     "signal flag can't be required",
+
 	ErrorCommandNameInvalid:   "invalid command name",
 	ErrorCommandNameNotUnique: "command name is not unique",
 
 	ErrorActionInvalid: "invalid action",
+
+	ErrorExampleInvalid:    "invalid example",
+	ErrorCommandLineSyntax: "wrong command line syntax",
+
+	ErrorInterrupted: "interrupted",
+	ErrorTimeout:     "timeout exceeded",
+	ErrorPanic:       "panic occurred",
+
+	ErrorFlagValueInvalid: "invalid flag value",
+	ErrorAborted:          "aborted by user",
+	ErrorStructInvalid:    "invalid struct declaration",
+	ErrorBindingMismatch:  "binding doesn't match declaration",
+}
+
+// ExitCoder can be implemented by errors returned from actions to control exit code of the process
+// See `stalk.ExitCode` for the codes used for other errors
+type ExitCoder interface {
+	error
+	// ExitCode returns exit code of the process
+	ExitCode() int
+}
+
+// PluginExitError is returned when plugin executable exits with non-zero status
+// The status becomes exit code of the process
+type PluginExitError struct {
+	// Plugin is a name of the executable
+	Plugin string
+	// Code is an exit status of the executable
+	Code int
+}
+
+// Error returns string representation of the error
+func (e PluginExitError) Error() string {
+	return "plugin '" + e.Plugin + "' exited with status " + strconv.Itoa(e.Code)
+}
+
+// ExitCode returns exit status of the plugin
+func (e PluginExitError) ExitCode() int {
+	return e.Code
 }
 
 // DeclarationErrors is an abstraction under error slice that used to pass found declaration errors as a single error
@@ -186,3 +301,46 @@ func (de DeclarationErrors) Error() string {
 	buf.WriteString(de[len(de)-1].Error())
 	return buf.String()
 }
+
+// LineError is an error of the line of the script executed by the workflow
+type LineError struct {
+	// Source is a name of the script, e.g. path of the file
+	Source string
+	// Line is a number of the line starting from 1, for continued lines it is a number of the first one
+	Line int
+	// Err is an error of the line
+	Err error
+}
+
+// Error returns string representation of the error prefixed with source and line, e.g. 'deploy.txt:3: ...'
+func (le LineError) Error() string {
+	return le.Source + ":" + strconv.Itoa(le.Line) + ": " + le.Err.Error()
+}
+
+// Unwrap returns error of the line
+func (le LineError) Unwrap() error {
+	return le.Err
+}
+
+// LineErrors is an abstraction under slice of errors of the script lines that used to pass them as a single error
+type LineErrors []LineError
+
+// Error returns string representation of errors separated by new line
+func (le LineErrors) Error() string {
+	buf := bytes.Buffer{}
+	for i, err := range le {
+		if i > 0 {
+			buf.WriteRune('\n')
+		}
+		buf.WriteString(err.Error())
+	}
+	return buf.String()
+}
+
+// Unwrap returns error of the first failed line
+func (le LineErrors) Unwrap() error {
+	if len(le) == 0 {
+		return nil
+	}
+	return le[0]
+}
@@ -1,10 +1,21 @@
 package common
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 var (
 	EmptyNameMessage    = "<empty name>"
 	ShortcutNotProvided rune
+	// MaskedValue replaces values of sensitive flags in string representations and error messages
+	MaskedValue = "******"
+	// FileFlagSuffix is added to the name of sensitive flag to read its value from the file, e.g. '--password-file'
+	FileFlagSuffix = "-file"
+	// StdinValue used as a value of sensitive flag reads the value from the input stream
+	StdinValue = "-"
+	// ConfirmationFlagName is a name of the flag that skips confirmation of the command
+	ConfirmationFlagName = "yes"
 )
 
 // common interface that describes all common parts of different flag types
@@ -35,10 +46,64 @@ type Flag interface {
 	WithDescription(value string) Flag
 	// GetDescription returns description message for this flag
 	GetDeclaredDescription() string
+	// Hidden sets this flag as hidden: it is accepted by parser but omitted from help and documentation
+	Hidden() Flag
+	// IsDeclaredHidden returns `true` if this flag is hidden
+	IsDeclaredHidden() bool
+	// Sensitive marks value of this flag as a secret, e.g. password or token
+	// Its value is masked in string representation, help and errors and is not echoed when prompted
+	// Value can be read from the file with '--<name>-file' flag or from input stream if it is '-'
+	Sensitive() Flag
+	// IsDeclaredSensitive returns `true` if value of this flag is a secret
+	IsDeclaredSensitive() bool
+	// WithEnv sets name of the environment variable used as a value of the flag if it is not provided in command line
+	// Signal flag is set if value of the variable is a true boolean, e.g. '1' or 'true'
+	WithEnv(name string) Flag
+	// GetDeclaredEnv returns name of the environment variable used as a value of the flag, empty if not set
+	GetDeclaredEnv() string
+	// Deprecated sets this flag as deprecated, a warning is emitted each time it is used
+	// If `replacement` is a name of the flag declared next to this one, value of this flag is forwarded to it
+	Deprecated(message, replacement string) Flag
+	// GetDeclaredDeprecation returns deprecation details or 'nil' if flag is not deprecated
+	GetDeclaredDeprecation() *Deprecation
 	// GetDeclarationErrors returns errors found in declaration of flag
 	GetDeclarationErrors() []error
 }
 
+// VisibleFlags returns flags that are not hidden
+func VisibleFlags(flags []Flag) []Flag {
+	var visible []Flag
+	for _, f := range flags {
+		if !f.IsDeclaredHidden() {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+// Redact returns `value` of the `flag` or `MaskedValue` if the flag is sensitive
+// It should be used to print values of flags, e.g. in audit logs
+func Redact(flag Flag, value string) string {
+	if sensitive, ok := flag.(Sensitive); ok && sensitive.IsDeclaredSensitive() {
+		return MaskedValue
+	}
+	return value
+}
+
+// IsShadowed returns `true` if any of `declared` flags has the same name or shortcut as `builtin` flag
+func IsShadowed(builtin Flag, declared []Flag) bool {
+	for _, f := range declared {
+		if f.GetName() == builtin.GetName() {
+			return true
+		}
+		shortcut := builtin.GetDeclaredShortcut()
+		if shortcut != ShortcutNotProvided && f.GetDeclaredShortcut() == shortcut {
+			return true
+		}
+	}
+	return false
+}
+
 // Custom interface can be used for user-defined specific flags and used in creation of `Workflow`
 type Custom interface {
 	Flag
@@ -46,6 +111,28 @@ type Custom interface {
 	Value() interface{}
 }
 
+// Typed helper interface that supply information about type and default value of the flag
+type Typed interface {
+	// GetDeclaredTypeName returns name of the flag value type, e.g. 'STRING', it is empty for signal flags
+	GetDeclaredTypeName() string
+	// GetDeclaredDefault returns default value of the flag or 'nil' if flag has no default
+	GetDeclaredDefault() interface{}
+}
+
+// Sensitive helper interface implemented by flags which values are secrets, e.g. passwords and tokens
+// Values of such flags are not echoed when user is prompted for them
+type Sensitive interface {
+	// IsDeclaredSensitive returns `true` if value of the flag is a secret
+	IsDeclaredSensitive() bool
+}
+
+// Resettable helper interface implemented by flags that can restore their default value
+// Flags are reset before parsing, so values don't leak between executions, e.g. in the shell
+type Resettable interface {
+	// Reset restores default value of the flag
+	Reset()
+}
+
 // ParsedString helper interface that supply `string` value
 type ParsedString interface {
 	// returns `string` value
@@ -69,3 +156,9 @@ type ParsedFloat interface {
 	// FloatValue returns parsed value
 	FloatValue() float64
 }
+
+// ParsedDuration helper interface that supply `time.Duration` value
+type ParsedDuration interface {
+	// DurationValue returns parsed value
+	DurationValue() time.Duration
+}
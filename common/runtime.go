@@ -1,18 +1,46 @@
 package common
 
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Action is a task executed with the runtime, e.g. main task of the command
+type Action func(ctx Runtime) error
+
+// Middleware wraps `next` action to add behaviour around it, e.g. logging, timing or authorization checks
+// Current command is available through `Runtime.CurrentCommand`
+type Middleware func(next Action) Action
+
 // Runtime provides access to provided list of flags for each command and to the global flags
 // It is also possible to use it as non-persistent key-value store between actions
 type Runtime interface {
 	// Run execute tasks with this context
 	Run() error
+	// Context returns context of the execution, it is cancelled when execution should be stopped
+	// The same context is observed by Setup, Before, Action, After, OnError and Cleanup actions
+	Context() context.Context
+	// Stdin returns input stream of the workflow, it is standard input unless other was declared
+	Stdin() io.Reader
+	// Stdout returns output stream of the workflow, it is standard output unless other was declared
+	Stdout() io.Writer
+	// Stderr returns error output stream of the workflow, it is standard error output unless other was declared
+	Stderr() io.Writer
+	// Render writes `value` into output stream in the format selected by the output flag of the workflow
+	Render(value interface{}) error
 	// GetArgs returns list of arguments provided to the command
 	GetArgs() []string
 	// CurrentCommand returns command under execution
 	CurrentCommand() ParsedCommand
+	// CommandPath returns names of the commands from the top-level one to the command under execution
+	CommandPath() []string
 	// Flags returns names of founded flags for command
 	Flags() []string
 	// GlobalFlags returns names of founded global flags
 	GlobalFlags() []string
+	// DeclaredGlobalFlags returns declarations of the global flags available to the commands, built-in ones included
+	DeclaredGlobalFlags() []Flag
 	// HasFlag returns `true` if flag with specified name was found in list of provided arguments
 	HasFlag(name string) bool
 	// HasGlobalFlag returns `true` if flag with specified name was found in list of provided arguments
@@ -33,6 +61,10 @@ type Runtime interface {
 	FloatFlag(name string) float64
 	// FloatGlobalFlag returns `float64` value provided for global flag with `name` name
 	FloatGlobalFlag(name string) float64
+	// DurationFlag returns `time.Duration` value provided for flag with `name` name for command
+	DurationFlag(name string) time.Duration
+	// DurationGlobalFlag returns `time.Duration` value provided for global flag with `name` name
+	DurationGlobalFlag(name string) time.Duration
 	// CustomFlag returns `interface{}` value provided for flag with `name` name for command
 	CustomFlag(name string) interface{}
 	// CustomGlobalFlag returns `interface{}` value provided for global flag with `name` name
@@ -0,0 +1,99 @@
+package stalk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+	"github.com/pavelmemory/stalk/help"
+	"github.com/pavelmemory/stalk/prompt"
+)
+
+func TestWorkflow_Run_Confirmation(t *testing.T) {
+	for index, scenario := range []struct {
+		flags    []common.Flag
+		prompter prompt.Prompter
+		args     []string
+		executed bool
+		cause    common.ErrorCode
+	}{
+		/*1*/ {nil, prompt.Scripted("y"), []string{"delete"}, true, 0},
+		/*2*/ {nil, prompt.Scripted("no"), []string{"delete"}, false, common.ErrorAborted},
+		/*3*/ {nil, nil, []string{"delete"}, false, common.ErrorAborted},
+		/*4*/ {nil, nil, []string{"delete", "--yes"}, true, 0},
+		/*5*/ {nil, nil, []string{"delete", "-y"}, true, 0},
+		/*6*/ {[]common.Flag{flag.String("year").WithShortcut('y')}, nil, []string{"delete", "--yes"}, true, 0},
+		/*7*/ {[]common.Flag{flag.BoolWithDefault("yes", false)}, nil, []string{"delete"}, false, common.ErrorAborted},
+		/*8*/ {[]common.Flag{flag.BoolWithDefault("yes", false)}, nil, []string{"delete", "--yes", "true"}, true, 0},
+		/*9*/ {[]common.Flag{flag.StringWithDefault("yes", "false")}, nil, []string{"delete", "--yes", "false"}, false, common.ErrorFlagNameInvalid},
+		/*10*/ {[]common.Flag{flag.Signal("yes")}, nil, []string{"delete", "--yes"}, true, 0},
+		/*11*/ {[]common.Flag{flag.Signal("yes")}, nil, []string{"delete"}, false, common.ErrorAborted},
+	} {
+		executed := false
+		var afterErr error
+		err := New().
+			WithIO(&bytes.Buffer{}, nil, &bytes.Buffer{}).
+			WithPrompter(scenario.prompter).
+			WithCommands(command.New("delete").
+				WithFlags(scenario.flags...).
+				WithConfirmation("delete everything?").
+				WithAction(func(ctx common.Runtime) error {
+					executed = true
+					return nil
+				}).
+				WithAfter(func(ctx common.Runtime, err error) {
+					afterErr = err
+				})).
+			Run(scenario.args)
+
+		if executed != scenario.executed {
+			t.Error("index:", index+1, "executed:", executed)
+		}
+		if scenario.cause == 0 {
+			if err != nil {
+				t.Error("index:", index+1, "unexpected error:", err)
+			}
+			continue
+		}
+		if scenario.cause == common.ErrorFlagNameInvalid {
+			// declaration error prevents execution, so after action is not called
+			declErrs, ok := err.(common.DeclarationErrors)
+			if !ok || len(declErrs) != 1 {
+				t.Error("index:", index+1, "unexpected error:", err)
+				continue
+			}
+			assertErrorCause(t, declErrs[0], scenario.cause)
+			continue
+		}
+		assertErrorCause(t, err, scenario.cause)
+		assertErrorCause(t, afterErr, scenario.cause)
+	}
+}
+
+func TestCommand_GetDeclaredFlags_ConfirmationFlagIdentity(t *testing.T) {
+	cmd := command.New("delete").WithFlags(flag.String("year").WithShortcut('y')).WithConfirmation("sure?")
+	first, second := cmd.GetDeclaredFlags(), cmd.GetDeclaredFlags()
+	if len(first) != 2 || len(second) != 2 || first[1] != second[1] {
+		t.Fatal("the same confirmation flag expected:", first, second)
+	}
+	if first[1].GetName() != common.ConfirmationFlagName || first[1].GetDeclaredShortcut() != common.ShortcutNotProvided {
+		t.Error("confirmation flag without shortcut expected:", first[1])
+	}
+}
+
+func TestWorkflow_Run_ConfirmationHelp(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := New().
+		WithHelpPrinter(help.New(buf).WithWidth(80)).
+		WithCommands(command.New("delete").WithConfirmation("sure?").WithAction(emptyAction)).
+		Run([]string{"delete", "--help"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "[--yes|-y]?") {
+		t.Error("confirmation flag expected in help:\n", buf.String())
+	}
+}
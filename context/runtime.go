@@ -1,25 +1,68 @@
 package context
 
 import (
-	"github.com/pavelmemory/stalk/common"
+	stdcontext "context"
+	"io"
 	"sync"
+	"time"
+
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/prompt"
+	"github.com/pavelmemory/stalk/render"
 )
 
+// Options contains workflow-level settings of the runtime
+type Options struct {
+	// Timeout limits execution time of all commands, 0 means no limit
+	Timeout time.Duration
+	// RecoverPanics enables conversion of panics in actions into errors caused by `common.ErrorPanic`
+	RecoverPanics bool
+	// Middlewares are applied to the main task of each command before middlewares of the commands
+	Middlewares []common.Middleware
+	// Stdin is an input stream available to actions
+	Stdin io.Reader
+	// Stdout is an output stream available to actions
+	Stdout io.Writer
+	// Stderr is an error output stream available to actions
+	Stderr io.Writer
+	// Encoder is used by `Render` to write values into `Stdout`, table format is used if not set
+	Encoder render.Encoder
+	// Storage is a key-value store used by `Set` and `Get`, it allows to share values between runtimes
+	// New storage is created if it is 'nil'
+	Storage map[interface{}]interface{}
+	// Prompter asks user for confirmation of commands declared with `WithConfirmation`
+	// If it is 'nil' or not interactive such commands require confirmation flag
+	Prompter prompt.Prompter
+	// GlobalFlags are declarations of the global flags available to the commands
+	GlobalFlags []common.Flag
+}
+
 type runtimeContext struct {
+	// guards context and current command
 	sync.RWMutex
+	ctx            stdcontext.Context
+	options        Options
 	globalFlags    map[string]common.Flag
 	rootCommand    common.ParsedCommand
 	currentCommand common.ParsedCommand
 	args           []string
 	storage        map[interface{}]interface{}
+	// storageLock guards storage, it is shared with snapshots of the runtime
+	storageLock *sync.RWMutex
 }
 
-func NewRuntimeContext(globalFlags []common.Flag, parsedCommand common.ParsedCommand, args []string) common.Runtime {
+func NewRuntimeContext(ctx stdcontext.Context, options Options, globalFlags []common.Flag, parsedCommand common.ParsedCommand, args []string) common.Runtime {
 	rc := &runtimeContext{
+		ctx:         ctx,
+		options:     options,
 		globalFlags: make(map[string]common.Flag),
-		storage:     make(map[interface{}]interface{}),
+		storage:     options.Storage,
 		rootCommand: parsedCommand,
 		args:        args,
+		storageLock: &sync.RWMutex{},
+	}
+	if rc.storage == nil {
+		rc.storage = make(map[interface{}]interface{})
 	}
 	for _, globalFlag := range globalFlags {
 		rc.globalFlags[globalFlag.GetName()] = globalFlag
@@ -27,63 +70,263 @@ func NewRuntimeContext(globalFlags []common.Flag, parsedCommand common.ParsedCom
 	return rc
 }
 
+// Snapshot returns runtime that keeps context and command under execution of `runtime` at the moment of the call
+// Storage is shared with `runtime`, but execution of the following commands and hooks doesn't change the snapshot,
+// so it can be passed to the code that runs concurrently with them
+func Snapshot(runtime common.Runtime) common.Runtime {
+	rc, ok := runtime.(*runtimeContext)
+	if !ok {
+		return runtime
+	}
+	return rc.snapshot()
+}
+
+func (rc *runtimeContext) snapshot() *runtimeContext {
+	rc.RLock()
+	defer rc.RUnlock()
+	return &runtimeContext{
+		ctx:            rc.ctx,
+		options:        rc.options,
+		globalFlags:    rc.globalFlags,
+		rootCommand:    rc.rootCommand,
+		currentCommand: rc.currentCommand,
+		args:           rc.args,
+		storage:        rc.storage,
+		storageLock:    rc.storageLock,
+	}
+}
+
 func (rc *runtimeContext) Run() error {
-	return rc.runCommand(rc.rootCommand)
+	if rc.options.Timeout > 0 {
+		defer rc.limitContext(rc.options.Timeout)()
+	}
+	return rc.runCommand(rc.rootCommand, rc.options.Middlewares)
 }
 
-func (rc *runtimeContext) runCommand(parsedCommand common.ParsedCommand) (err error) {
+// runCommand executes command with its child commands
+// `middlewares` are declared by the workflow and parent commands, they wrap middlewares of this command
+func (rc *runtimeContext) runCommand(parsedCommand common.ParsedCommand, middlewares []common.Middleware) (err error) {
 	if parsedCommand == nil {
 		return
 	}
-	rc.currentCommand = parsedCommand
+	rc.setCommand(parsedCommand)
 
+	// child commands are completed before this deferred call, so their `OnError` and `After` actions go first
 	defer func() {
-		rc.currentCommand = parsedCommand
+		rc.setCommand(parsedCommand)
 		if err != nil {
 			if onError := parsedCommand.GetDeclaredOnError(); onError != nil {
-				onError(rc, err)
+				if panicErr := rc.callHook(onError, err); panicErr != nil {
+					err = panicErr
+				}
 			}
+		}
 
-			if afterAction := parsedCommand.GetDeclaredAfter(); afterAction != nil {
-				afterAction(rc, err)
+		if afterAction := parsedCommand.GetDeclaredAfter(); afterAction != nil {
+			if panicErr := rc.callHook(afterAction, err); panicErr != nil {
+				err = panicErr
 			}
 		}
 	}()
 
+	if err := rc.confirm(parsedCommand); err != nil {
+		return err
+	}
+
+	// restoring of the context happens before `OnError` and `After` actions, so they are not limited by the timeout
+	if timeout := parsedCommand.GetDeclaredTimeout(); timeout > 0 {
+		defer rc.limitContext(timeout)()
+	}
+
 	if beforeAction := parsedCommand.GetDeclaredBefore(); beforeAction != nil {
-		if err := beforeAction(rc); err != nil {
+		if err := rc.await(parsedCommand, beforeAction); err != nil {
 			return err
 		}
 	}
 
+	middlewares = append(append([]common.Middleware(nil), middlewares...), parsedCommand.GetDeclaredMiddlewares()...)
 	if action := parsedCommand.GetDeclaredAction(); action != nil {
-		err = action(rc)
+		wrapped := common.Action(action)
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			wrapped = middlewares[i](wrapped)
+		}
+		err = rc.await(parsedCommand, wrapped)
 	}
 
 	if err == nil {
 		parsedSubCommand := parsedCommand.GetSubCommand()
-		err = rc.runCommand(parsedSubCommand)
+		err = rc.runCommand(parsedSubCommand, middlewares)
 	}
 
 	return
 }
 
+// command returns command under execution
+func (rc *runtimeContext) command() common.ParsedCommand {
+	rc.RLock()
+	defer rc.RUnlock()
+	return rc.currentCommand
+}
+
+func (rc *runtimeContext) setCommand(parsedCommand common.ParsedCommand) {
+	rc.Lock()
+	rc.currentCommand = parsedCommand
+	rc.Unlock()
+}
+
+func (rc *runtimeContext) Context() stdcontext.Context {
+	rc.RLock()
+	defer rc.RUnlock()
+	return rc.ctx
+}
+
+// confirm asks user for confirmation if it is required by the command and was not skipped with the flag
+func (rc *runtimeContext) confirm(parsedCommand common.ParsedCommand) error {
+	message := parsedCommand.GetDeclaredConfirmation()
+	if message == "" {
+		return nil
+	}
+	if f, found := parsedCommand.GetFlags()[common.ConfirmationFlagName]; found {
+		// declared boolean flag with the same name skips confirmation only if it is set to `true`,
+		// flags of other types are rejected by declaration validation and never skip it
+		if f.IsDeclaredSignal() {
+			return nil
+		}
+		if typed, ok := f.(common.Typed); ok && typed.GetDeclaredTypeName() == "BOOL" && f.(common.ParsedBool).BoolValue() {
+			return nil
+		}
+	}
+
+	prompter := rc.options.Prompter
+	if prompter == nil || !prompter.IsInteractive() {
+		return common.AbortedError("confirmation of command '" + parsedCommand.GetName() + "' required, use '--" + common.ConfirmationFlagName + "' to proceed")
+	}
+	confirmed, err := prompter.Confirm(message)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return common.AbortedError("command '" + parsedCommand.GetName() + "'")
+	}
+	return nil
+}
+
+// limitContext replaces context of the runtime with one limited by `timeout`
+// Returned function restores previous context
+func (rc *runtimeContext) limitContext(timeout time.Duration) func() {
+	rc.Lock()
+	parent := rc.ctx
+	ctx, cancel := stdcontext.WithTimeout(parent, timeout)
+	rc.ctx = ctx
+	rc.Unlock()
+	return func() {
+		cancel()
+		rc.Lock()
+		rc.ctx = parent
+		rc.Unlock()
+	}
+}
+
+// await executes `action` and waits for its completion
+// If context of the runtime has deadline and it is exceeded before `action` completes,
+// the action is abandoned and `ErrorTimeout` error returned
+// Limited action gets a snapshot of the runtime, so the abandoned one doesn't observe the following hooks
+func (rc *runtimeContext) await(parsedCommand common.ParsedCommand, action func(ctx common.Runtime) error) error {
+	ctx := rc.Context()
+	if _, limited := ctx.Deadline(); !limited {
+		return rc.call(action)
+	}
+
+	snapshot := rc.snapshot()
+	done := make(chan error, 1)
+	go func() {
+		done <- snapshot.call(action)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && ctx.Err() == stdcontext.DeadlineExceeded {
+			return common.TimeoutError("command '" + parsedCommand.GetName() + "'")
+		}
+		return err
+	case <-ctx.Done():
+		if ctx.Err() == stdcontext.DeadlineExceeded {
+			return common.TimeoutError("command '" + parsedCommand.GetName() + "'")
+		}
+		// cancelled by the caller, action is expected to observe it
+		return <-done
+	}
+}
+
+// call executes `action` and converts its panic into error if it is enabled by options
+func (rc *runtimeContext) call(action func(ctx common.Runtime) error) (err error) {
+	if rc.options.RecoverPanics {
+		defer common.RecoverPanic(&err)
+	}
+	return action(rc)
+}
+
+// callHook executes `hook` and returns error only if it panicked and recovering is enabled by options
+func (rc *runtimeContext) callHook(hook func(ctx common.Runtime, err error), err error) (panicErr error) {
+	if rc.options.RecoverPanics {
+		defer common.RecoverPanic(&panicErr)
+	}
+	hook(rc, err)
+	return nil
+}
+
+func (rc *runtimeContext) Stdin() io.Reader {
+	return rc.options.Stdin
+}
+
+func (rc *runtimeContext) Stdout() io.Writer {
+	return rc.options.Stdout
+}
+
+func (rc *runtimeContext) Stderr() io.Writer {
+	return rc.options.Stderr
+}
+
+func (rc *runtimeContext) Render(value interface{}) error {
+	encoder := rc.options.Encoder
+	if encoder == nil {
+		encoder = render.Table()
+	}
+	return encoder.Encode(rc.Stdout(), value)
+}
+
 func (rc *runtimeContext) GetArgs() []string {
 	return rc.args
 }
 
 func (rc *runtimeContext) CurrentCommand() common.ParsedCommand {
-	return rc.currentCommand
+	return rc.command()
+}
+
+func (rc *runtimeContext) CommandPath() []string {
+	var path []string
+	current := rc.command()
+	for cmd := rc.rootCommand; cmd != nil; cmd = cmd.GetSubCommand() {
+		path = append(path, cmd.GetName())
+		if cmd == current {
+			break
+		}
+	}
+	return path
 }
 
 func (rc *runtimeContext) Flags() []string {
 	var flagNames []string
-	for flagName := range rc.currentCommand.GetFlags() {
+	for flagName := range rc.command().GetFlags() {
 		flagNames = append(flagNames, flagName)
 	}
 	return flagNames
 }
 
+func (rc *runtimeContext) DeclaredGlobalFlags() []common.Flag {
+	return rc.options.GlobalFlags
+}
+
 func (rc *runtimeContext) GlobalFlags() []string {
 	var flagNames []string
 	for flagName := range rc.globalFlags {
@@ -93,22 +336,22 @@ func (rc *runtimeContext) GlobalFlags() []string {
 }
 
 func (rc *runtimeContext) Set(key interface{}, value interface{}) (oldValue interface{}, overridden bool) {
-	rc.Lock()
+	rc.storageLock.Lock()
 	oldValue, overridden = rc.storage[key]
 	rc.storage[key] = value
-	rc.Unlock()
+	rc.storageLock.Unlock()
 	return
 }
 
 func (rc *runtimeContext) Get(key interface{}) (value interface{}, found bool) {
-	rc.RLock()
+	rc.storageLock.RLock()
 	value, found = rc.storage[key]
-	rc.RUnlock()
+	rc.storageLock.RUnlock()
 	return
 }
 
 func (rc *runtimeContext) HasFlag(name string) (found bool) {
-	_, found = rc.currentCommand.GetFlags()[name]
+	_, found = rc.command().GetFlags()[name]
 	return
 }
 
@@ -118,14 +361,14 @@ func (rc *runtimeContext) HasGlobalFlag(name string) (found bool) {
 }
 
 func (rc *runtimeContext) StringFlag(name string) string {
-	return stringFromMap(name, rc.currentCommand.GetFlags())
+	return stringFromMap(name, rc.command().GetFlags())
 }
 
 func (rc *runtimeContext) StringGlobalFlag(name string) string {
 	return stringFromMap(name, rc.globalFlags)
 }
 func (rc *runtimeContext) IntFlag(name string) int64 {
-	return intFromMap(name, rc.currentCommand.GetFlags())
+	return intFromMap(name, rc.command().GetFlags())
 }
 
 func (rc *runtimeContext) IntGlobalFlag(name string) int64 {
@@ -133,7 +376,7 @@ func (rc *runtimeContext) IntGlobalFlag(name string) int64 {
 }
 
 func (rc *runtimeContext) FloatFlag(name string) float64 {
-	return floatFromMap(name, rc.currentCommand.GetFlags())
+	return floatFromMap(name, rc.command().GetFlags())
 }
 
 func (rc *runtimeContext) FloatGlobalFlag(name string) float64 {
@@ -141,15 +384,23 @@ func (rc *runtimeContext) FloatGlobalFlag(name string) float64 {
 }
 
 func (rc *runtimeContext) BoolFlag(name string) bool {
-	return boolFromMap(name, rc.currentCommand.GetFlags())
+	return boolFromMap(name, rc.command().GetFlags())
 }
 
 func (rc *runtimeContext) BoolGlobalFlag(name string) bool {
 	return boolFromMap(name, rc.globalFlags)
 }
 
+func (rc *runtimeContext) DurationFlag(name string) time.Duration {
+	return durationFromMap(name, rc.command().GetFlags())
+}
+
+func (rc *runtimeContext) DurationGlobalFlag(name string) time.Duration {
+	return durationFromMap(name, rc.globalFlags)
+}
+
 func (rc *runtimeContext) CustomFlag(name string) interface{} {
-	if f, found := rc.currentCommand.GetFlags()[name]; found {
+	if f, found := rc.command().GetFlags()[name]; found {
 		return f.(common.Custom).Value()
 	}
 	return nil
@@ -189,3 +440,10 @@ func floatFromMap(name string, m map[string]common.Flag) float64 {
 	}
 	return float64(0)
 }
+
+func durationFromMap(name string, m map[string]common.Flag) time.Duration {
+	if f, found := m[name]; found {
+		return f.(common.ParsedDuration).DurationValue()
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package stalk
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pavelmemory/stalk/common"
+)
+
+// Schema is a machine-readable description of the workflow declaration
+type Schema struct {
+	// Name of the application
+	Name string `json:"name"`
+	// Description of the application
+	Description string `json:"description,omitempty"`
+	// GlobalFlags are declared global flags followed by built-in ones that are not shadowed by them
+	GlobalFlags []FlagSchema `json:"globalFlags,omitempty"`
+	// Commands are top-level commands
+	Commands []CommandSchema `json:"commands,omitempty"`
+}
+
+// CommandSchema describes declared command
+type CommandSchema struct {
+	// Name of the command
+	Name string `json:"name"`
+	// Description of the command
+	Description string `json:"description,omitempty"`
+	// Hidden is `true` if command is omitted from help and documentation
+	Hidden bool `json:"hidden,omitempty"`
+	// Deprecation is 'nil' if command is not deprecated
+	Deprecation *common.Deprecation `json:"deprecation,omitempty"`
+	// Examples of the command usage
+	Examples []common.Example `json:"examples,omitempty"`
+	// Flags of the command
+	Flags []FlagSchema `json:"flags,omitempty"`
+	// Commands are child commands
+	Commands []CommandSchema `json:"commands,omitempty"`
+}
+
+// FlagSchema describes declared flag
+type FlagSchema struct {
+	// Name of the flag
+	Name string `json:"name"`
+	// Shortcut of the flag, empty if it is not declared
+	Shortcut string `json:"shortcut,omitempty"`
+	// Type is a name of the value type, e.g. 'STRING', it is empty for signal flags and flags not implementing `common.Typed`
+	Type string `json:"type,omitempty"`
+	// Signal is `true` for flags without value
+	Signal bool `json:"signal,omitempty"`
+	// Required is `true` if flag must be provided
+	Required bool `json:"required,omitempty"`
+	// Default value of the flag, 'nil' if flag has no default or it is unknown
+	// Durations are represented as strings, e.g. '1m0s', defaults of sensitive flags are masked
+	Default interface{} `json:"default,omitempty"`
+	// Description of the flag
+	Description string `json:"description,omitempty"`
+	// Env is a name of the environment variable used as a value of the flag
+	Env string `json:"env,omitempty"`
+	// Hidden is `true` if flag is omitted from help and documentation
+	Hidden bool `json:"hidden,omitempty"`
+	// Sensitive is `true` if value of the flag is a secret
+	Sensitive bool `json:"sensitive,omitempty"`
+	// Deprecation is 'nil' if flag is not deprecated
+	Deprecation *common.Deprecation `json:"deprecation,omitempty"`
+}
+
+// WriteJSON writes schema into `w` as indented JSON document
+func (s Schema) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+func (w *workflow) Describe() Schema {
+	globalFlags := w.GetDeclaredGlobalFlags()
+	for _, builtin := range []common.Flag{w.GetDeclaredOutputFlag(), w.GetDeclaredScriptFlag(), w.GetDeclaredHelpFlag()} {
+		if builtin != nil && !common.IsShadowed(builtin, w.GetDeclaredGlobalFlags()) {
+			globalFlags = append(globalFlags[:len(globalFlags):len(globalFlags)], builtin)
+		}
+	}
+
+	return Schema{
+		Name:        w.GetDeclaredName(),
+		Description: w.GetDeclaredDescription(),
+		GlobalFlags: describeFlags(globalFlags),
+		Commands:    describeCommands(w.GetDeclaredCommands()),
+	}
+}
+
+func describeCommands(commands []common.CommandDeclaration) []CommandSchema {
+	var schemas []CommandSchema
+	for _, cmd := range commands {
+		schemas = append(schemas, CommandSchema{
+			Name:        cmd.GetName(),
+			Description: cmd.GetDeclaredDescription(),
+			Hidden:      cmd.IsDeclaredHidden(),
+			Deprecation: cmd.GetDeclaredDeprecation(),
+			Examples:    cmd.GetDeclaredExamples(),
+			Flags:       describeFlags(cmd.GetDeclaredFlags()),
+			Commands:    describeCommands(cmd.GetDeclaredSubCommands()),
+		})
+	}
+	return schemas
+}
+
+func describeFlags(flags []common.Flag) []FlagSchema {
+	var schemas []FlagSchema
+	for _, f := range flags {
+		schema := FlagSchema{
+			Name:        f.GetName(),
+			Signal:      f.IsDeclaredSignal(),
+			Required:    f.IsDeclaredRequired(),
+			Description: f.GetDeclaredDescription(),
+			Env:         f.GetDeclaredEnv(),
+			Hidden:      f.IsDeclaredHidden(),
+			Deprecation: f.GetDeclaredDeprecation(),
+		}
+		if f.GetDeclaredShortcut() != common.ShortcutNotProvided {
+			schema.Shortcut = string(f.GetDeclaredShortcut())
+		}
+		if sensitive, ok := f.(common.Sensitive); ok {
+			schema.Sensitive = sensitive.IsDeclaredSensitive()
+		}
+		if typed, ok := f.(common.Typed); ok {
+			schema.Type = typed.GetDeclaredTypeName()
+			if f.HasDefault() {
+				schema.Default = describeDefault(typed.GetDeclaredDefault(), schema.Sensitive)
+			}
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas
+}
+
+// describeDefault returns representation of the default value suitable for serialization
+func describeDefault(value interface{}, sensitive bool) interface{} {
+	if sensitive {
+		return common.MaskedValue
+	}
+	if duration, ok := value.(time.Duration); ok {
+		return duration.String()
+	}
+	return value
+}
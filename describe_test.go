@@ -0,0 +1,129 @@
+package stalk
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestWorkflow_Describe(t *testing.T) {
+	action := func(ctx common.Runtime) error { return nil }
+	w := New().
+		WithName("app").
+		WithDescription("manages servers").
+		WithGlobalFlags(flag.Signal("verbose").WithShortcut('v').WithEnv("APP_VERBOSE")).
+		WithCommands(
+			command.New("server").
+				WithDescription("server operations").
+				WithSubCommands(
+					command.New("create").
+						WithExample("small server", "server create --name web --size 1").
+						WithFlags(
+							flag.String("name").Required(true).WithDescription("name of the server"),
+							flag.IntWithDefault("size", 2),
+							flag.DurationWithDefault("timeout", time.Minute).Hidden(),
+							flag.StringWithDefault("token", "secret").Sensitive(),
+						).
+						WithAction(action),
+					command.New("start").Deprecated("servers start on creation", "server create").WithAction(action),
+				),
+			command.New("debug").Hidden().WithAction(action),
+		)
+
+	buf := &bytes.Buffer{}
+	if err := w.Describe().WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+  "name": "app",
+  "description": "manages servers",
+  "globalFlags": [
+    {
+      "name": "verbose",
+      "shortcut": "v",
+      "signal": true,
+      "env": "APP_VERBOSE"
+    },
+    {
+      "name": "output",
+      "shortcut": "o",
+      "type": "STRING",
+      "default": "table",
+      "description": "output format: table, json, yaml, csv or template=TEXT"
+    },
+    {
+      "name": "script",
+      "type": "STRING",
+      "description": "execute command lines from the file, '-' reads them from the input"
+    },
+    {
+      "name": "help",
+      "shortcut": "h",
+      "signal": true,
+      "description": "show help information"
+    }
+  ],
+  "commands": [
+    {
+      "name": "server",
+      "description": "server operations",
+      "commands": [
+        {
+          "name": "create",
+          "examples": [
+            {
+              "description": "small server",
+              "commandLine": "server create --name web --size 1"
+            }
+          ],
+          "flags": [
+            {
+              "name": "name",
+              "type": "STRING",
+              "required": true,
+              "description": "name of the server"
+            },
+            {
+              "name": "size",
+              "type": "INT",
+              "default": 2
+            },
+            {
+              "name": "timeout",
+              "type": "DURATION",
+              "default": "1m0s",
+              "hidden": true
+            },
+            {
+              "name": "token",
+              "type": "STRING",
+              "default": "******",
+              "sensitive": true
+            }
+          ]
+        },
+        {
+          "name": "start",
+          "deprecation": {
+            "message": "servers start on creation",
+            "replacement": "server create"
+          }
+        }
+      ]
+    },
+    {
+      "name": "debug",
+      "hidden": true
+    }
+  ]
+}
+`
+	if actual := buf.String(); expected != actual {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+}
@@ -0,0 +1,386 @@
+// Package docs generates Markdown and HTML reference documentation for the workflow commands
+package docs
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/help"
+)
+
+// Page is a generated reference page of the application or one of its commands
+type Page struct {
+	// Name is a name of the page built from the command path, e.g. 'app_aws_create'
+	Name string
+	// Title is a human readable title of the page, e.g. 'app aws create'
+	Title string
+	// CommandPath contains names of the commands from the top-level one to the documented one
+	CommandPath []string
+	// Markdown is a page content in Markdown format
+	Markdown []byte
+	// HTML is a page content in HTML format, it is empty if HTML generation is not enabled
+	HTML []byte
+}
+
+// Generator produces reference pages for the application and each command path of the workflow
+type Generator interface {
+	// WithHTML enables generation of HTML pages along with Markdown
+	WithHTML(enabled bool) Generator
+	// WithFrontMatter sets function that returns front matter placed at the beginning of each Markdown page
+	// It is called with page that has no content yet, empty result means no front matter
+	WithFrontMatter(frontMatter func(page Page) string) Generator
+	// Generate returns pages for the application and each command path
+	Generate() []Page
+	// WriteTo writes generated pages into directory `dir` as '<name>.md' and '<name>.html' files
+	WriteTo(dir string) error
+}
+
+// New creates generator of reference pages for the `workflow`
+func New(workflow stalk.Workflow) Generator {
+	return &generator{workflow: workflow}
+}
+
+// YAMLFrontMatter returns front matter hook that produces YAML block with page title
+// It is suitable for most of static site generators such as Jekyll or Hugo
+func YAMLFrontMatter(extra map[string]string) func(page Page) string {
+	return func(page Page) string {
+		buf := &bytes.Buffer{}
+		buf.WriteString("---\n")
+		fmt.Fprintf(buf, "title: %q\n", page.Title)
+		for _, key := range sortedKeys(extra) {
+			fmt.Fprintf(buf, "%s: %q\n", key, extra[key])
+		}
+		buf.WriteString("---\n")
+		return buf.String()
+	}
+}
+
+var _ Generator = (*generator)(nil)
+
+type generator struct {
+	workflow    stalk.Workflow
+	html        bool
+	frontMatter func(page Page) string
+}
+
+func (g *generator) WithHTML(enabled bool) Generator {
+	g.html = enabled
+	return g
+}
+
+func (g *generator) WithFrontMatter(frontMatter func(page Page) string) Generator {
+	g.frontMatter = frontMatter
+	return g
+}
+
+func (g *generator) Generate() []Page {
+	pages := []Page{g.page(nil)}
+	return append(pages, g.commandPages(nil, common.VisibleCommands(g.workflow.GetDeclaredCommands()))...)
+}
+
+func (g *generator) WriteTo(dir string) error {
+	for _, page := range g.Generate() {
+		if err := ioutil.WriteFile(filepath.Join(dir, page.Name+".md"), page.Markdown, 0644); err != nil {
+			return err
+		}
+		if !g.html {
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, page.Name+".html"), page.HTML, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) commandPages(parent []common.CommandDeclaration, commands []common.CommandDeclaration) []Page {
+	var pages []Page
+	for _, cmd := range commands {
+		path := append(append([]common.CommandDeclaration(nil), parent...), cmd)
+		pages = append(pages, g.page(path))
+		pages = append(pages, g.commandPages(path, common.VisibleCommands(cmd.GetDeclaredSubCommands()))...)
+	}
+	return pages
+}
+
+// reference is a format independent content of the page
+type reference struct {
+	appName     string
+	title       string
+	synopsis    string
+	description string
+	flags       []common.Flag
+	globalFlags []common.Flag
+	commands    []link
+	parent      *link
+	examples    []common.Example
+}
+
+type link struct {
+	title       string
+	name        string
+	description string
+}
+
+func (g *generator) page(path []common.CommandDeclaration) Page {
+	appName := g.workflow.GetDeclaredName()
+	globalFlags := help.GlobalFlags(g.workflow.GetDeclaredGlobalFlags(), g.workflow.GetDeclaredOutputFlag(), g.workflow.GetDeclaredScriptFlag(), g.workflow.GetDeclaredHelpFlag())
+	ref := reference{
+		appName:     appName,
+		title:       title(appName, path),
+		description: g.workflow.GetDeclaredDescription(),
+		globalFlags: globalFlags,
+		synopsis: help.Synopsis(help.Usage{
+			Name:        appName,
+			GlobalFlags: globalFlags,
+			Commands:    g.workflow.GetDeclaredCommands(),
+			Path:        path,
+		}),
+	}
+
+	commands := common.VisibleCommands(g.workflow.GetDeclaredCommands())
+	if len(path) != 0 {
+		cmd := path[len(path)-1]
+		ref.description = cmd.GetDeclaredDescription()
+		ref.flags = common.VisibleFlags(cmd.GetDeclaredFlags())
+		ref.examples = cmd.GetDeclaredExamples()
+		commands = common.VisibleCommands(cmd.GetDeclaredSubCommands())
+
+		parentPath := path[:len(path)-1]
+		parentDescription := g.workflow.GetDeclaredDescription()
+		if len(parentPath) != 0 {
+			parentDescription = parentPath[len(parentPath)-1].GetDeclaredDescription()
+		}
+		ref.parent = &link{
+			title:       title(appName, parentPath),
+			name:        pageName(appName, parentPath),
+			description: parentDescription,
+		}
+	}
+	for _, cmd := range commands {
+		childPath := append(append([]common.CommandDeclaration(nil), path...), cmd)
+		ref.commands = append(ref.commands, link{
+			title:       cmd.GetName(),
+			name:        pageName(appName, childPath),
+			description: cmd.GetDeclaredDescription(),
+		})
+	}
+
+	page := Page{Name: pageName(appName, path), Title: ref.title}
+	for _, cmd := range path {
+		page.CommandPath = append(page.CommandPath, cmd.GetName())
+	}
+
+	frontMatter := ""
+	if g.frontMatter != nil {
+		frontMatter = g.frontMatter(page)
+	}
+	page.Markdown = markdown(frontMatter, ref)
+	if g.html {
+		page.HTML = htmlPage(ref)
+	}
+	return page
+}
+
+func markdown(frontMatter string, ref reference) []byte {
+	buf := &bytes.Buffer{}
+	if frontMatter != "" {
+		buf.WriteString(frontMatter)
+		if !strings.HasSuffix(frontMatter, "\n") {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("# " + ref.title + "\n\n")
+	if ref.description != "" {
+		buf.WriteString(ref.description + "\n\n")
+	}
+	buf.WriteString("## Usage\n\n```\n" + ref.synopsis + "\n```\n")
+
+	if len(ref.flags) != 0 {
+		buf.WriteString("\n## Flags\n\n")
+		markdownFlags(buf, ref.flags)
+	}
+	if len(ref.globalFlags) != 0 {
+		buf.WriteString("\n## Global flags\n\n")
+		markdownFlags(buf, ref.globalFlags)
+	}
+
+	if len(ref.examples) != 0 {
+		buf.WriteString("\n## Examples\n")
+		for _, example := range ref.examples {
+			buf.WriteString("\n")
+			if example.Description != "" {
+				buf.WriteString(example.Description + "\n\n")
+			}
+			buf.WriteString("```\n$ " + ref.appName + " " + example.CommandLine + "\n```\n")
+		}
+	}
+
+	if len(ref.commands) != 0 {
+		buf.WriteString("\n## Commands\n\n")
+		buf.WriteString("| Command | Description |\n")
+		buf.WriteString("|---------|-------------|\n")
+		for _, cmd := range ref.commands {
+			buf.WriteString("| [" + cell(cmd.title) + "](" + cmd.name + ".md) | " + cell(cmd.description) + " |\n")
+		}
+	}
+
+	if ref.parent != nil {
+		buf.WriteString("\n## See also\n\n")
+		buf.WriteString("* [" + ref.parent.title + "](" + ref.parent.name + ".md)")
+		if ref.parent.description != "" {
+			buf.WriteString(" - " + strings.Split(ref.parent.description, "\n")[0])
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func markdownFlags(buf *bytes.Buffer, flags []common.Flag) {
+	buf.WriteString("| Name | Shortcut | Type | Default | Environment | Required | Description |\n")
+	buf.WriteString("|------|----------|------|---------|-------------|----------|-------------|\n")
+	for _, f := range flags {
+		info := describeFlag(f)
+		buf.WriteString("| `" + info.name + "` | " + code(info.shortcut) + " | " + cell(info.typeName) + " | " +
+			code(info.defaultValue) + " | " + code(info.env) + " | " + info.required + " | " + cell(info.description) + " |\n")
+	}
+}
+
+func htmlPage(ref reference) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	buf.WriteString("<title>" + html.EscapeString(ref.title) + "</title>\n</head>\n<body>\n")
+	buf.WriteString("<h1>" + html.EscapeString(ref.title) + "</h1>\n")
+	if ref.description != "" {
+		buf.WriteString("<p>" + strings.Replace(html.EscapeString(ref.description), "\n", "<br>\n", -1) + "</p>\n")
+	}
+	buf.WriteString("<h2>Usage</h2>\n<pre><code>" + html.EscapeString(ref.synopsis) + "</code></pre>\n")
+
+	if len(ref.flags) != 0 {
+		buf.WriteString("<h2>Flags</h2>\n")
+		htmlFlags(buf, ref.flags)
+	}
+	if len(ref.globalFlags) != 0 {
+		buf.WriteString("<h2>Global flags</h2>\n")
+		htmlFlags(buf, ref.globalFlags)
+	}
+
+	if len(ref.examples) != 0 {
+		buf.WriteString("<h2>Examples</h2>\n")
+		for _, example := range ref.examples {
+			if example.Description != "" {
+				buf.WriteString("<p>" + html.EscapeString(example.Description) + "</p>\n")
+			}
+			buf.WriteString("<pre><code>$ " + html.EscapeString(ref.appName+" "+example.CommandLine) + "</code></pre>\n")
+		}
+	}
+
+	if len(ref.commands) != 0 {
+		buf.WriteString("<h2>Commands</h2>\n<table>\n<tr><th>Command</th><th>Description</th></tr>\n")
+		for _, cmd := range ref.commands {
+			buf.WriteString("<tr><td><a href=\"" + html.EscapeString(cmd.name) + ".html\">" + html.EscapeString(cmd.title) + "</a></td>" +
+				"<td>" + html.EscapeString(cmd.description) + "</td></tr>\n")
+		}
+		buf.WriteString("</table>\n")
+	}
+
+	if ref.parent != nil {
+		buf.WriteString("<h2>See also</h2>\n<ul>\n<li><a href=\"" + html.EscapeString(ref.parent.name) + ".html\">" +
+			html.EscapeString(ref.parent.title) + "</a></li>\n</ul>\n")
+	}
+	buf.WriteString("</body>\n</html>\n")
+	return buf.Bytes()
+}
+
+func htmlFlags(buf *bytes.Buffer, flags []common.Flag) {
+	buf.WriteString("<table>\n<tr><th>Name</th><th>Shortcut</th><th>Type</th><th>Default</th><th>Environment</th><th>Required</th><th>Description</th></tr>\n")
+	for _, f := range flags {
+		info := describeFlag(f)
+		buf.WriteString("<tr>")
+		for _, value := range []string{info.name, info.shortcut, info.typeName, info.defaultValue, info.env, info.required, info.description} {
+			buf.WriteString("<td>" + html.EscapeString(value) + "</td>")
+		}
+		buf.WriteString("</tr>\n")
+	}
+	buf.WriteString("</table>\n")
+}
+
+type flagInfo struct {
+	name         string
+	shortcut     string
+	typeName     string
+	defaultValue string
+	env          string
+	required     string
+	description  string
+}
+
+func describeFlag(f common.Flag) flagInfo {
+	info := flagInfo{
+		name:        "--" + f.GetName(),
+		env:         f.GetDeclaredEnv(),
+		required:    "no",
+		description: f.GetDeclaredDescription(),
+	}
+	if f.GetDeclaredShortcut() != common.ShortcutNotProvided {
+		info.shortcut = "-" + string(f.GetDeclaredShortcut())
+	}
+	if f.IsDeclaredRequired() {
+		info.required = "yes"
+	}
+	if f.IsDeclaredSignal() {
+		info.typeName = "SIGNAL"
+	}
+	if typed, ok := f.(common.Typed); ok {
+		if typeName := typed.GetDeclaredTypeName(); typeName != "" {
+			info.typeName = typeName
+		}
+		if f.HasDefault() {
+			info.defaultValue = common.Redact(f, fmt.Sprint(typed.GetDeclaredDefault()))
+		}
+	}
+	return info
+}
+
+func title(appName string, path []common.CommandDeclaration) string {
+	parts := []string{appName}
+	for _, cmd := range path {
+		parts = append(parts, cmd.GetName())
+	}
+	return strings.Join(parts, " ")
+}
+
+func pageName(appName string, path []common.CommandDeclaration) string {
+	return strings.Replace(title(appName, path), " ", "_", -1)
+}
+
+// cell escapes text to be placed into Markdown table cell
+func cell(text string) string {
+	text = strings.Replace(text, "|", "\\|", -1)
+	return strings.Replace(text, "\n", "<br>", -1)
+}
+
+func code(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`" + cell(text) + "`"
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
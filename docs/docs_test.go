@@ -0,0 +1,69 @@
+package docs
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	stalkflag "github.com/pavelmemory/stalk/flag"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerator_Generate(t *testing.T) {
+	emptyAction := func(ctx common.Runtime) error { return nil }
+	wf := stalk.New().
+		WithName("app").
+		WithDescription("manages cloud resources").
+		WithGlobalFlags(stalkflag.Signal("verbose").WithShortcut('v').WithEnv("APP_VERBOSE").WithDescription("print more details")).
+		WithCommands(
+			command.New("aws").
+				WithDescription("works with aws resources").
+				WithSubCommands(
+					command.New("create").
+						WithDescription("creates new resource").
+						WithFlags(
+							stalkflag.String("name").WithShortcut('n').Required(true).WithDescription("name of the resource"),
+							stalkflag.IntWithDefault("count", 1).WithDescription("number of resources | pipes are escaped"),
+							stalkflag.StringWithDefault("token", "s3cr3t").Sensitive().WithEnv("APP_TOKEN").WithDescription("access token")).
+						WithExample("create resource named 'test'", "aws create --name test").
+						WithAction(emptyAction)))
+
+	pages := New(wf).
+		WithHTML(true).
+		WithFrontMatter(YAMLFrontMatter(map[string]string{"layout": "reference"})).
+		Generate()
+
+	expectedNames := []string{"app", "app_aws", "app_aws_create"}
+	if len(pages) != len(expectedNames) {
+		t.Fatal("\nexpected:\n", expectedNames, "\nactual:\n", pages)
+	}
+	for i, page := range pages {
+		if page.Name != expectedNames[i] {
+			t.Error("\nexpected:\n", expectedNames[i], "\nactual:\n", page.Name)
+		}
+		assertGolden(t, page.Name+".md", page.Markdown)
+		assertGolden(t, page.Name+".html", page.HTML)
+	}
+}
+
+func assertGolden(t *testing.T, name string, actual []byte) {
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != string(actual) {
+		t.Error(name, "\nexpected:\n", string(expected), "\nactual:\n", string(actual))
+	}
+}
@@ -0,0 +1,74 @@
+package stalk
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestWorkflow_Run_Env(t *testing.T) {
+	t.Setenv("STALK_TEST_HOST", "from-env")
+	t.Setenv("STALK_TEST_PORT", "8080")
+	t.Setenv("STALK_TEST_DEBUG", "1")
+	t.Setenv("STALK_TEST_EMPTY", "")
+
+	for index, scenario := range []struct {
+		args     []string
+		quiet    string
+		expected string
+		err      string
+	}{
+		/*1*/ {[]string{"connect"}, "false", "from-env:8080 debug=true quiet=false user=", ""},
+		/*2*/ {[]string{"connect", "--host", "inline", "--user", "admin", "--port", "1"}, "", "inline:1 debug=true quiet=false user=admin", ""},
+		/*3*/ {[]string{"connect"}, "maybe", "", "invalid flag value: --quiet: strconv.ParseBool: parsing \"maybe\": invalid syntax"},
+	} {
+		t.Setenv("STALK_TEST_QUIET", scenario.quiet)
+		var actual string
+		err := New().
+			WithCommands(command.New("connect").
+				WithFlags(
+					flag.String("host").Required(true).WithEnv("STALK_TEST_HOST"),
+					flag.IntWithDefault("port", 22).WithEnv("STALK_TEST_PORT"),
+					flag.Signal("debug").WithEnv("STALK_TEST_DEBUG"),
+					flag.Signal("quiet").WithEnv("STALK_TEST_QUIET"),
+					flag.String("user").WithEnv("STALK_TEST_EMPTY"),
+				).
+				WithAction(func(ctx common.Runtime) error {
+					actual = fmt.Sprintf("%s:%d debug=%t quiet=%t user=%s",
+						ctx.StringFlag("host"), ctx.IntFlag("port"), ctx.HasFlag("debug"), ctx.HasFlag("quiet"), ctx.StringFlag("user"))
+					return nil
+				})).
+			Run(scenario.args)
+		actualErr := ""
+		if err != nil {
+			actualErr = err.Error()
+		}
+		if scenario.err != actualErr {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.err, "\nactual:\n", actualErr)
+		}
+		if scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestWorkflow_Run_EnvHelp(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := New().
+		WithIO(nil, out, nil).
+		WithCommands(command.New("connect").
+			WithFlags(flag.String("host").WithEnv("APP_HOST").WithDescription("host to connect to")).
+			WithAction(func(ctx common.Runtime) error { return nil })).
+		Run([]string{"connect", "--help"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "host to connect to (env: APP_HOST)") {
+		t.Error("env is not shown in help:\n", out.String())
+	}
+}
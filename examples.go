@@ -0,0 +1,58 @@
+package stalk
+
+import (
+	stdcontext "context"
+
+	"github.com/pavelmemory/stalk/common"
+)
+
+// ValidateExamples parses command line of each declared command example the same way `Run` does
+// and returns errors for examples that can't be parsed or lead to another command
+// Parsing sets values of declared flags, so it is intended to be used in tests of the application
+func ValidateExamples(workflow Workflow) []error {
+	return validateExamples(workflow, nil, workflow.GetDeclaredCommands())
+}
+
+func validateExamples(workflow Workflow, parent []common.CommandDeclaration, commands []common.CommandDeclaration) []error {
+	var errs []error
+	for _, cmd := range commands {
+		path := append(append([]common.CommandDeclaration(nil), parent...), cmd)
+		for _, example := range cmd.GetDeclaredExamples() {
+			if err := validateExample(workflow, path, example); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		errs = append(errs, validateExamples(workflow, path, cmd.GetDeclaredSubCommands())...)
+	}
+	return errs
+}
+
+func validateExample(workflow Workflow, path []common.CommandDeclaration, example common.Example) error {
+	prefix := commandPath(path) + ": '" + example.CommandLine + "': "
+
+	args, err := splitCommandLine(example.CommandLine)
+	if err != nil {
+		return common.ExampleInvalidError(prefix + err.Error())
+	}
+
+	inv, err := parse(stdcontext.Background(), workflow, args, session{})
+	if err != nil {
+		return common.ExampleInvalidError(prefix + err.Error())
+	}
+	if !samePath(path, inv.path) {
+		return common.ExampleInvalidError(prefix + "leads to another command")
+	}
+	return nil
+}
+
+func samePath(expected, actual []common.CommandDeclaration) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			return false
+		}
+	}
+	return true
+}
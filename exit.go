@@ -0,0 +1,97 @@
+package stalk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/pavelmemory/stalk/common"
+)
+
+// Exit codes returned by `ExitCode` and used by `Main`
+const (
+	// ExitOK signals successful execution
+	ExitOK = 0
+	// ExitFailure signals that action, hook or setup ended with an error
+	ExitFailure = 1
+	// ExitUsage signals that command line arguments are invalid: unknown command or flag, missing value, etc.
+	ExitUsage = 2
+	// ExitDeclaration signals that workflow declaration is invalid and nothing was executed
+	ExitDeclaration = 3
+	// ExitAborted signals that user declined confirmation of the command
+	ExitAborted = 4
+	// ExitTimeout signals that execution was not completed in declared time
+	ExitTimeout = 124
+	// ExitInterrupted signals that execution was interrupted by the signal
+	ExitInterrupted = 130
+)
+
+// Main runs `workflow` with command line arguments of the process,
+// prints error if any into error output stream of the workflow and exits with code returned by `ExitCode`
+func Main(workflow Workflow) {
+	MainWithExit(workflow, os.Exit)
+}
+
+// MainWithExit does the same as `Main`, but terminates the process with `exit` instead of `os.Exit`
+func MainWithExit(workflow Workflow, exit func(code int)) {
+	err := workflow.Run(os.Args[1:])
+	if err != nil {
+		_, _, errOut := workflow.GetDeclaredIO()
+		fmt.Fprintln(errOut, workflow.GetDeclaredName()+": "+err.Error())
+	}
+	exit(ExitCode(err))
+}
+
+// ExitCode maps error returned by the workflow to the exit code of the process
+// Errors implementing `common.ExitCoder` define exit code by themselves
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitCoder common.ExitCoder
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+
+	if _, ok := err.(common.DeclarationErrors); ok {
+		return ExitDeclaration
+	}
+
+	var stalkErr common.Error
+	if !errors.As(err, &stalkErr) {
+		return ExitFailure
+	}
+	switch stalkErr.Cause {
+	case common.ErrorNotImplemented,
+		common.ErrorNotAllRequiredValues,
+		common.ErrorNotAllRequiredFlags,
+		common.ErrorFlagSyntax,
+		common.ErrorFlagNotSupported,
+		common.ErrorFlagValueInvalid,
+		common.ErrorCommandLineSyntax:
+		return ExitUsage
+	case common.ErrorFlagShortcutInvalid,
+		common.ErrorFlagShortcutNotUnique,
+		common.ErrorFlagShortcutNameSame,
+		common.ErrorFlagNameInvalid,
+		common.ErrorFlagNameNotUnique,
+		common.ErrorFlagRequiredAndHasDefault,
+		common.ErrorFlagSignalAndRequired,
+		common.ErrorCommandNameInvalid,
+		common.ErrorCommandNameNotUnique,
+		common.ErrorActionInvalid,
+		common.ErrorExampleInvalid,
+		common.ErrorStructInvalid,
+		common.ErrorBindingMismatch:
+		return ExitDeclaration
+	case common.ErrorAborted:
+		return ExitAborted
+	case common.ErrorTimeout:
+		return ExitTimeout
+	case common.ErrorInterrupted:
+		return ExitInterrupted
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,89 @@
+package stalk
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+type exitCodeError int
+
+func (e exitCodeError) Error() string {
+	return "exit code error"
+}
+
+func (e exitCodeError) ExitCode() int {
+	return int(e)
+}
+
+func TestExitCode(t *testing.T) {
+	for index, scenario := range []struct {
+		err      error
+		expected int
+	}{
+		/*1*/ {nil, ExitOK},
+		/*2*/ {errors.New("failed"), ExitFailure},
+		/*3*/ {common.FlagNotSupportedError("--unknown"), ExitUsage},
+		/*4*/ {common.NotImplementedError("command: 'unknown'"), ExitUsage},
+		/*5*/ {common.DeclarationErrors{common.FlagNameInvalidError("")}, ExitDeclaration},
+		/*6*/ {common.CommandNameNotUniqueError("cmd"), ExitDeclaration},
+		/*7*/ {common.TimeoutError("command 'cmd'"), ExitTimeout},
+		/*8*/ {common.InterruptedError("interrupt"), ExitInterrupted},
+		/*9*/ {common.PanicError("boom", nil), ExitFailure},
+		/*10*/ {exitCodeError(42), 42},
+		/*11*/ {common.AbortedError("command 'cmd'"), ExitAborted},
+		/*12*/ {common.LineError{Source: "script", Line: 2, Err: common.FlagNotSupportedError("--unknown")}, ExitUsage},
+		/*13*/ {common.LineErrors{{Source: "script", Line: 1, Err: errors.New("failed")}, {Source: "script", Line: 2, Err: common.FlagNotSupportedError("--unknown")}}, ExitFailure},
+	} {
+		if actual := ExitCode(scenario.err); actual != scenario.expected {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestMain_ExitCode(t *testing.T) {
+	defer func(args []string) {
+		os.Args = args
+	}(os.Args)
+	var code int
+	exit := func(c int) {
+		code = c
+	}
+
+	errOut := &bytes.Buffer{}
+	workflow := New().
+		WithName("app").
+		WithIO(nil, nil, errOut).
+		WithCommands(command.New("cmd").
+			WithFlags(flag.Int("count")).
+			WithAction(func(ctx common.Runtime) error {
+				return exitCodeError(7)
+			}))
+
+	for index, scenario := range []struct {
+		args     []string
+		expected int
+	}{
+		/*1*/ {[]string{"app"}, ExitOK},
+		/*2*/ {[]string{"app", "cmd"}, 7},
+		/*3*/ {[]string{"app", "cmd", "--count", "many"}, ExitUsage},
+		/*4*/ {[]string{"app", "unknown"}, ExitUsage},
+	} {
+		code = -1
+		os.Args = scenario.args
+		MainWithExit(workflow, exit)
+		if code != scenario.expected {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", code)
+		}
+	}
+
+	if !strings.HasPrefix(errOut.String(), "app: exit code error\n") {
+		t.Error("error expected in the error output stream, got:", errOut.String())
+	}
+}
@@ -2,6 +2,7 @@ package flag
 
 import (
 	"strconv"
+	"time"
 
 	"fmt"
 	"github.com/pavelmemory/stalk/common"
@@ -26,18 +27,26 @@ var (
 			shortcut = shortcut[:len(shortcut)-1]
 		}
 
-		fimpl := flag.(*impl)
+		typeName, defaultValue := "VALUE", interface{}(nil)
+		if typed, ok := flag.(common.Typed); ok {
+			typeName, defaultValue = typed.GetDeclaredTypeName(), typed.GetDeclaredDefault()
+		}
 		if flag.HasDefault() {
-			return name + shortcut + " <" + fimpl.valueTypeName + ", " + fmt.Sprint(fimpl.value) + ">"
+			return name + shortcut + " <" + typeName + ", " + common.Redact(flag, fmt.Sprint(defaultValue)) + ">"
 		}
-		return name + shortcut + " [" + fimpl.valueTypeName + "]"
+		return name + shortcut + " [" + typeName + "]"
 	}
 
-	_ common.Flag         = (*impl)(nil)
-	_ common.ParsedString = (*impl)(nil)
-	_ common.ParsedInt    = (*impl)(nil)
-	_ common.ParsedBool   = (*impl)(nil)
-	_ common.ParsedFloat  = (*impl)(nil)
+	_ common.Flag           = (*impl)(nil)
+	_ common.Typed          = (*impl)(nil)
+	_ common.Sensitive      = (*impl)(nil)
+	_ common.Resettable     = (*impl)(nil)
+	_ fmt.GoStringer        = (*impl)(nil)
+	_ common.ParsedString   = (*impl)(nil)
+	_ common.ParsedInt      = (*impl)(nil)
+	_ common.ParsedBool     = (*impl)(nil)
+	_ common.ParsedFloat    = (*impl)(nil)
+	_ common.ParsedDuration = (*impl)(nil)
 )
 
 type impl struct {
@@ -46,11 +55,16 @@ type impl struct {
 	required      bool
 	proceed       func(value string) error
 	value         interface{}
+	defaultValue  interface{}
 	valueTypeName string
 	signal        bool
 	hasDefault    bool
 	stringerProv  func(flag common.Flag) string
 	description   string
+	hidden        bool
+	sensitive     bool
+	env           string
+	deprecation   *common.Deprecation
 	declErrs      []error
 }
 
@@ -130,6 +144,22 @@ func (f *impl) FloatValue() float64 {
 	return f.value.(float64)
 }
 
+func (f *impl) DurationValue() time.Duration {
+	return f.value.(time.Duration)
+}
+
+func (f *impl) GetDeclaredTypeName() string {
+	return f.valueTypeName
+}
+
+func (f *impl) GetDeclaredDefault() interface{} {
+	return f.defaultValue
+}
+
+func (f *impl) Reset() {
+	f.value = f.defaultValue
+}
+
 func (f *impl) WithStringer(stringer func(flag common.Flag) string) common.Flag {
 	f.stringerProv = stringer
 	return f
@@ -159,6 +189,47 @@ func (f *impl) GetDeclaredDescription() string {
 	return f.description
 }
 
+func (f *impl) Hidden() common.Flag {
+	f.hidden = true
+	return f
+}
+
+func (f *impl) IsDeclaredHidden() bool {
+	return f.hidden
+}
+
+func (f *impl) Sensitive() common.Flag {
+	f.sensitive = true
+	return f
+}
+
+func (f *impl) IsDeclaredSensitive() bool {
+	return f.sensitive
+}
+
+func (f *impl) WithEnv(name string) common.Flag {
+	f.env = name
+	return f
+}
+
+func (f *impl) GetDeclaredEnv() string {
+	return f.env
+}
+
+// GoString returns string representation of the flag used by '%#v' verb, so debug output doesn't reveal values
+func (f *impl) GoString() string {
+	return f.String()
+}
+
+func (f *impl) Deprecated(message, replacement string) common.Flag {
+	f.deprecation = &common.Deprecation{Message: message, Replacement: replacement}
+	return f
+}
+
+func (f *impl) GetDeclaredDeprecation() *common.Deprecation {
+	return f.deprecation
+}
+
 func (f *impl) GetDeclarationErrors() []error {
 	return f.declErrs
 }
@@ -228,10 +299,25 @@ func BoolWithDefault(name string, value bool) common.Flag {
 	return setDefault(Bool(name), value)
 }
 
+func Duration(name string) common.Flag {
+	f := &impl{valueTypeName: "DURATION"}
+	f.Name(name)
+	f.proceed = func(value string) (err error) {
+		f.value, err = time.ParseDuration(value)
+		return
+	}
+	return f
+}
+
+func DurationWithDefault(name string, value time.Duration) common.Flag {
+	return setDefault(Duration(name), value)
+}
+
 func setDefault(f common.Flag, value interface{}) common.Flag {
 	fi := f.(*impl)
 	fi.hasDefault = true
 	fi.value = value
+	fi.defaultValue = value
 	if fi.IsDeclaredRequired() {
 		fi.declErrs = append(fi.declErrs, common.FlagRequiredAndHasDefaultError(f.GetName()))
 	}
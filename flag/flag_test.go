@@ -1,7 +1,9 @@
 package flag
 
 import (
+	"fmt"
 	"github.com/pavelmemory/stalk/common"
+	"strings"
 	"testing"
 )
 
@@ -17,6 +19,7 @@ func TestDefaultFlagStringer(t *testing.T) {
 		/*5*/ {"[--verbose]?", Signal("verbose")},
 		/*6*/ {"[--verbose|-v]?", Signal("verbose").WithShortcut('v')},
 		/*7*/ {"[--verbose|-v]?", Signal("verbose").WithShortcut('v')},
+		/*8*/ {"[--token]? <STRING, ******>", StringWithDefault("token", "s3cr3t").Sensitive()},
 	} {
 		actual := DefaultFlagStringer(scenario.flag)
 		if scenario.expected != actual {
@@ -24,3 +27,10 @@ func TestDefaultFlagStringer(t *testing.T) {
 		}
 	}
 }
+
+func TestSensitive_GoString(t *testing.T) {
+	token := StringWithDefault("token", "s3cr3t").Sensitive()
+	if dump := fmt.Sprintf("%#v %+v", token, token); strings.Contains(dump, "s3cr3t") {
+		t.Error("sensitive value revealed:", dump)
+	}
+}
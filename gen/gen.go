@@ -0,0 +1,400 @@
+// Package gen generates Go code with typed bindings of the workflow flags
+// Each command gets a struct with fields for its flags and arguments and a `Bind` method that fills them from runtime,
+// so names of the flags are checked by compiler instead of being read by string names
+// `Bind` returns error if declaration was changed after generation, but it happens only at runtime,
+// use `Generator.Check` in a test of the package with generated code to detect outdated bindings at build time
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/common"
+)
+
+// GlobalName is a name of the generated struct with global flags
+const GlobalName = "Global"
+
+// argsField is a name of the field that receives arguments of the command
+const argsField = "Args"
+
+// Generator produces Go source code of the bindings for the workflow
+type Generator interface {
+	// WithPackage sets name of the package of generated code, default is 'cli'
+	WithPackage(name string) Generator
+	// WithSource sets description of the source of the declaration mentioned in the header, e.g. file name of the specification
+	WithSource(source string) Generator
+	// Generate returns formatted source code
+	// It returns error if names of commands or flags can't be turned into unique Go identifiers
+	Generate() ([]byte, error)
+	// Check returns error caused by `common.ErrorBindingMismatch` if code in the file located by `path`
+	// is different from the generated one, e.g. because declaration was changed after generation
+	Check(path string) error
+}
+
+// New creates generator of the bindings for the `workflow`
+func New(workflow stalk.Workflow) Generator {
+	return &generator{workflow: workflow, pkg: "cli"}
+}
+
+var _ Generator = (*generator)(nil)
+
+type generator struct {
+	workflow stalk.Workflow
+	pkg      string
+	source   string
+}
+
+func (g *generator) WithPackage(name string) Generator {
+	g.pkg = name
+	return g
+}
+
+func (g *generator) WithSource(source string) Generator {
+	g.source = source
+	return g
+}
+
+func (g *generator) Generate() ([]byte, error) {
+	schema := g.workflow.Describe()
+	if errs := g.workflow.GetDeclarationErrors(); len(errs) != 0 {
+		return nil, common.DeclarationErrors(errs)
+	}
+
+	var bindings []binding
+	global, err := newBinding(GlobalName, "", nil, runtimeGlobalFlags(g.workflow, schema.GlobalFlags))
+	if err != nil {
+		return nil, err
+	}
+	bindings = append(bindings, global)
+	if bindings, err = commandBindings(bindings, nil, schema.Commands); err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]string)
+	for _, b := range bindings {
+		subject := "global flags"
+		if b.path != nil {
+			subject = "command '" + strings.Join(b.path, " ") + "'"
+		}
+		if other, found := types[b.typeName]; found {
+			return nil, common.BindingMismatchError("type " + b.typeName + " of " + subject + " conflicts with " + other)
+		}
+		types[b.typeName] = subject
+	}
+
+	buf := &bytes.Buffer{}
+	header := "Code generated by stalk-gen. DO NOT EDIT."
+	if g.source != "" {
+		header = "Code generated by stalk-gen from " + g.source + ". DO NOT EDIT."
+	}
+	fmt.Fprintf(buf, "// %s\n\npackage %s\n\nimport (\n", header, g.pkg)
+	if usesDuration(bindings) {
+		buf.WriteString("\"time\"\n\n")
+	}
+	buf.WriteString("\"github.com/pavelmemory/stalk/common\"\n")
+	buf.WriteString("\"github.com/pavelmemory/stalk/gen\"\n")
+	buf.WriteString(")\n")
+	for _, b := range bindings {
+		b.write(buf)
+	}
+	return format.Source(buf.Bytes())
+}
+
+func (g *generator) Check(path string) error {
+	code, err := g.Generate()
+	if err != nil {
+		return err
+	}
+	existing, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(code, existing) {
+		return common.BindingMismatchError("code in '" + path + "' is outdated, it must be generated again")
+	}
+	return nil
+}
+
+// runtimeGlobalFlags returns described global `flags` available to actions: declared ones and output flag
+// Script and help flags are handled by the workflow itself
+func runtimeGlobalFlags(workflow stalk.Workflow, flags []stalk.FlagSchema) []stalk.FlagSchema {
+	declared := len(workflow.GetDeclaredGlobalFlags())
+	result := flags[:declared:declared]
+	if output := workflow.GetDeclaredOutputFlag(); output != nil {
+		for _, f := range flags[declared:] {
+			if f.Name == output.GetName() {
+				result = append(result, f)
+			}
+		}
+	}
+	return result
+}
+
+func commandBindings(bindings []binding, parent []string, commands []stalk.CommandSchema) ([]binding, error) {
+	for _, cmd := range commands {
+		path := append(append([]string(nil), parent...), cmd.Name)
+		typeName, err := identifier(strings.Join(path, " "))
+		if err != nil {
+			return nil, err
+		}
+		b, err := newBinding(typeName, cmd.Description, path, cmd.Flags)
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, b)
+		if bindings, err = commandBindings(bindings, path, cmd.Commands); err != nil {
+			return nil, err
+		}
+	}
+	return bindings, nil
+}
+
+// binding is a generated struct of the command or of the global flags if `path` is empty
+type binding struct {
+	typeName    string
+	description string
+	path        []string
+	fields      []field
+}
+
+type field struct {
+	name string
+	flag stalk.FlagSchema
+}
+
+func newBinding(typeName, description string, path []string, flags []stalk.FlagSchema) (binding, error) {
+	b := binding{typeName: typeName, description: description, path: path}
+	names := make(map[string]string)
+	if path != nil {
+		names[argsField] = "arguments"
+	}
+	for _, f := range flags {
+		name, err := identifier(f.Name)
+		if err != nil {
+			return binding{}, err
+		}
+		if other, found := names[name]; found {
+			return binding{}, common.BindingMismatchError("field " + name + " of " + typeName + " for flag '--" + f.Name + "' conflicts with " + other)
+		}
+		names[name] = "flag '--" + f.Name + "'"
+		b.fields = append(b.fields, field{name: name, flag: f})
+	}
+	return b, nil
+}
+
+func (b binding) global() bool {
+	return b.path == nil
+}
+
+func (b binding) write(buf *bytes.Buffer) {
+	if b.global() {
+		fmt.Fprintf(buf, "\n// %s contains values of the global flags\n", b.typeName)
+	} else {
+		fmt.Fprintf(buf, "\n// %s contains values of the flags and arguments of '%s' command", b.typeName, strings.Join(b.path, " "))
+		if line := firstLine(b.description); line != "" {
+			buf.WriteString(": " + line)
+		}
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(buf, "type %s struct {\n", b.typeName)
+	for _, f := range b.fields {
+		fmt.Fprintf(buf, "// %s is a value of '--%s' flag", f.name, f.flag.Name)
+		if line := firstLine(f.flag.Description); line != "" {
+			buf.WriteString(": " + line)
+		}
+		fmt.Fprintf(buf, "\n%s %s\n", f.name, goType(f.flag))
+	}
+	if !b.global() {
+		fmt.Fprintf(buf, "// %s are arguments of the command\n%s []string\n", argsField, argsField)
+	}
+	buf.WriteString("}\n")
+
+	if b.global() {
+		fmt.Fprintf(buf, "\n// Bind fills fields with values of the global flags provided to `ctx`\n")
+		fmt.Fprintf(buf, "// It returns error if the global flags don't match the declaration the code was generated from\n")
+		fmt.Fprintf(buf, "func (v *%s) Bind(ctx common.Runtime) error {\n", b.typeName)
+		buf.WriteString("if err := gen.VerifyGlobal(ctx, []gen.Flag{\n")
+	} else {
+		fmt.Fprintf(buf, "\n// Bind fills fields with values of the flags and arguments of the command under execution in `ctx`\n")
+		fmt.Fprintf(buf, "// It returns error if the command doesn't match the declaration the code was generated from\n")
+		fmt.Fprintf(buf, "func (v *%s) Bind(ctx common.Runtime) error {\n", b.typeName)
+		fmt.Fprintf(buf, "if err := gen.Verify(ctx, %#v, []gen.Flag{\n", b.path)
+	}
+	for _, f := range b.fields {
+		if f.flag.Signal {
+			fmt.Fprintf(buf, "{Name: %q, Signal: true},\n", f.flag.Name)
+		} else {
+			fmt.Fprintf(buf, "{Name: %q, Type: %q},\n", f.flag.Name, f.flag.Type)
+		}
+	}
+	buf.WriteString("}); err != nil {\nreturn err\n}\n")
+	for _, f := range b.fields {
+		fmt.Fprintf(buf, "v.%s = ctx.%s(%q)\n", f.name, accessor(f.flag, b.global()), f.flag.Name)
+	}
+	if !b.global() {
+		fmt.Fprintf(buf, "v.%s = ctx.GetArgs()\n", argsField)
+	}
+	buf.WriteString("return nil\n}\n")
+}
+
+func goType(f stalk.FlagSchema) string {
+	if f.Signal {
+		return "bool"
+	}
+	switch f.Type {
+	case "STRING":
+		return "string"
+	case "INT":
+		return "int64"
+	case "FLOAT":
+		return "float64"
+	case "BOOL":
+		return "bool"
+	case "DURATION":
+		return "time.Duration"
+	default:
+		return "interface{}"
+	}
+}
+
+// accessor returns name of the `common.Runtime` method that returns value of the flag
+func accessor(f stalk.FlagSchema, global bool) string {
+	kind := "Custom"
+	switch {
+	case f.Signal:
+		kind = "Has"
+	case f.Type == "STRING":
+		kind = "String"
+	case f.Type == "INT":
+		kind = "Int"
+	case f.Type == "FLOAT":
+		kind = "Float"
+	case f.Type == "BOOL":
+		kind = "Bool"
+	case f.Type == "DURATION":
+		kind = "Duration"
+	}
+	if global {
+		return kind + "GlobalFlag"
+	}
+	return kind + "Flag"
+}
+
+func usesDuration(bindings []binding) bool {
+	for _, b := range bindings {
+		for _, f := range b.fields {
+			if goType(f.flag) == "time.Duration" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// identifier converts name of the command or flag into exported Go identifier, e.g. 'dry-run' into 'DryRun'
+func identifier(name string) (string, error) {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	id := b.String()
+	if id == "" || !unicode.IsUpper([]rune(id)[0]) {
+		return "", common.BindingMismatchError("can't generate exported identifier for " + strconv.Quote(name))
+	}
+	return id, nil
+}
+
+func firstLine(text string) string {
+	return strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+}
+
+// Flag is a flag of the command expected by generated binding
+type Flag struct {
+	// Name of the flag
+	Name string
+	// Type is a name of the flag value type, see `common.Typed`
+	Type string
+	// Signal is `true` for flags without value
+	Signal bool
+}
+
+// Verify returns error caused by `common.ErrorBindingMismatch` if path of the command under execution in `ctx`
+// is not `path` or its declared flags are different from `flags`
+// It is used by generated code to detect declarations changed after generation
+func Verify(ctx common.Runtime, path []string, flags []Flag) error {
+	command := strings.Join(path, " ")
+	if actual := strings.Join(ctx.CommandPath(), " "); actual != command {
+		return common.BindingMismatchError("binding of command '" + command + "' is used with command '" + actual + "'")
+	}
+	return compare("command '"+command+"'", ctx.CurrentCommand().GetDeclaredFlags(), flags)
+}
+
+// VerifyGlobal returns error caused by `common.ErrorBindingMismatch` if global flags declared for `ctx`
+// are different from `flags`
+func VerifyGlobal(ctx common.Runtime, flags []Flag) error {
+	return compare("global flags", ctx.DeclaredGlobalFlags(), flags)
+}
+
+// compare returns error describing differences between declared flags of the `subject` and expected `flags`
+func compare(subject string, declaredFlags []common.Flag, flags []Flag) error {
+	declared := make(map[string]Flag)
+	for _, f := range declaredFlags {
+		expected := Flag{Name: f.GetName(), Signal: f.IsDeclaredSignal()}
+		if typed, ok := f.(common.Typed); ok {
+			expected.Type = typed.GetDeclaredTypeName()
+		}
+		declared[f.GetName()] = expected
+	}
+
+	var problems []string
+	for _, f := range flags {
+		actual, found := declared[f.Name]
+		switch {
+		case !found:
+			problems = append(problems, "flag '--"+f.Name+"' is not declared")
+		case actual != f:
+			problems = append(problems, "flag '--"+f.Name+"' is declared as "+typeDescription(actual)+" instead of "+typeDescription(f))
+		}
+		delete(declared, f.Name)
+	}
+	var unbound []string
+	for name := range declared {
+		unbound = append(unbound, name)
+	}
+	sort.Strings(unbound)
+	for _, name := range unbound {
+		problems = append(problems, "flag '--"+name+"' is not bound")
+	}
+
+	if len(problems) != 0 {
+		return common.BindingMismatchError(subject + ": " + strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func typeDescription(f Flag) string {
+	if f.Signal {
+		return "signal"
+	}
+	if f.Type == "" {
+		return "custom"
+	}
+	return f.Type
+}
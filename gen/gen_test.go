@@ -0,0 +1,96 @@
+package gen
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	stalkflag "github.com/pavelmemory/stalk/flag"
+	"github.com/pavelmemory/stalk/spec"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerator_Generate(t *testing.T) {
+	emptyAction := func(ctx common.Runtime) error { return nil }
+	wf, err := spec.Load(filepath.Join("testdata", "app.yaml"), spec.Registry{"create": emptyAction, "list": emptyAction})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := New(wf).WithSource("app.yaml").Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// generated code is kept as a package, so it is compiled and tested as well
+	golden := filepath.Join("internal", "cli", "cli_gen.go")
+	if *update {
+		if err := ioutil.WriteFile(golden, code, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != string(code) {
+		t.Error("\nexpected:\n", string(expected), "\nactual:\n", string(code))
+	}
+}
+
+func TestGenerator_Check(t *testing.T) {
+	emptyAction := func(ctx common.Runtime) error { return nil }
+	wf, err := spec.Load(filepath.Join("testdata", "app.yaml"), spec.Registry{"create": emptyAction, "list": emptyAction})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generator := New(wf).WithSource("app.yaml")
+
+	golden := filepath.Join("internal", "cli", "cli_gen.go")
+	if err := generator.Check(golden); err != nil {
+		t.Error("generated code expected to be up to date, got:", err)
+	}
+
+	wf.WithGlobalFlags(stalkflag.Signal("verbose"), stalkflag.String("region"))
+	err = generator.Check(golden)
+	if cErr, ok := err.(common.Error); !ok || cErr.Cause != common.ErrorBindingMismatch {
+		t.Error("binding mismatch expected for changed declaration, got:", err)
+	}
+}
+
+func TestGenerator_Generate_Errors(t *testing.T) {
+	emptyAction := func(ctx common.Runtime) error { return nil }
+
+	for index, scenario := range []struct {
+		workflow stalk.Workflow
+		expected string
+	}{
+		/*1*/ {stalk.New().WithCommands(command.New("server-list").WithAction(emptyAction), command.New("server").WithSubCommands(command.New("list").WithAction(emptyAction))),
+			"binding doesn't match declaration: type ServerList of command 'server list' conflicts with command 'server-list'"},
+		/*2*/ {stalk.New().WithCommands(command.New("global").WithAction(emptyAction)),
+			"binding doesn't match declaration: type Global of command 'global' conflicts with global flags"},
+		/*3*/ {stalk.New().WithCommands(command.New("copy").WithFlags(stalkflag.String("args")).WithAction(emptyAction)),
+			"binding doesn't match declaration: field Args of Copy for flag '--args' conflicts with arguments"},
+		/*4*/ {stalk.New().WithCommands(command.New("copy").WithFlags(stalkflag.String("dry-run"), stalkflag.String("dry_run")).WithAction(emptyAction)),
+			"binding doesn't match declaration: field DryRun of Copy for flag '--dry_run' conflicts with flag '--dry-run'"},
+		/*5*/ {stalk.New().WithCommands(command.New("2fa").WithAction(emptyAction)),
+			"binding doesn't match declaration: can't generate exported identifier for \"2fa\""},
+		/*6*/ {stalk.New().WithCommands(command.New("empty")),
+			"invalid action: command 'empty' has no action neither sub-commands to execute"},
+	} {
+		_, err := New(scenario.workflow).Generate()
+		actual := ""
+		if err != nil {
+			actual = err.Error()
+		}
+		if scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Code generated by stalk-gen from app.yaml. DO NOT EDIT.
+
+package cli
+
+import (
+	"time"
+
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/gen"
+)
+
+// Global contains values of the global flags
+type Global struct {
+	// Verbose is a value of '--verbose' flag: print more details
+	Verbose bool
+	// Output is a value of '--output' flag: output format: table, json, yaml, csv or template=TEXT
+	Output string
+}
+
+// Bind fills fields with values of the global flags provided to `ctx`
+// It returns error if the global flags don't match the declaration the code was generated from
+func (v *Global) Bind(ctx common.Runtime) error {
+	if err := gen.VerifyGlobal(ctx, []gen.Flag{
+		{Name: "verbose", Signal: true},
+		{Name: "output", Type: "STRING"},
+	}); err != nil {
+		return err
+	}
+	v.Verbose = ctx.HasGlobalFlag("verbose")
+	v.Output = ctx.StringGlobalFlag("output")
+	return nil
+}
+
+// Server contains values of the flags and arguments of 'server' command: works with servers
+type Server struct {
+	// Args are arguments of the command
+	Args []string
+}
+
+// Bind fills fields with values of the flags and arguments of the command under execution in `ctx`
+// It returns error if the command doesn't match the declaration the code was generated from
+func (v *Server) Bind(ctx common.Runtime) error {
+	if err := gen.Verify(ctx, []string{"server"}, []gen.Flag{}); err != nil {
+		return err
+	}
+	v.Args = ctx.GetArgs()
+	return nil
+}
+
+// ServerCreate contains values of the flags and arguments of 'server create' command: creates new server
+type ServerCreate struct {
+	// Name is a value of '--name' flag: name of the server
+	Name string
+	// Size is a value of '--size' flag
+	Size int64
+	// PriceLimit is a value of '--price-limit' flag
+	PriceLimit float64
+	// DryRun is a value of '--dry-run' flag
+	DryRun bool
+	// Backup is a value of '--backup' flag
+	Backup bool
+	// Timeout is a value of '--timeout' flag
+	Timeout time.Duration
+	// Args are arguments of the command
+	Args []string
+}
+
+// Bind fills fields with values of the flags and arguments of the command under execution in `ctx`
+// It returns error if the command doesn't match the declaration the code was generated from
+func (v *ServerCreate) Bind(ctx common.Runtime) error {
+	if err := gen.Verify(ctx, []string{"server", "create"}, []gen.Flag{
+		{Name: "name", Type: "STRING"},
+		{Name: "size", Type: "INT"},
+		{Name: "price-limit", Type: "FLOAT"},
+		{Name: "dry-run", Signal: true},
+		{Name: "backup", Type: "BOOL"},
+		{Name: "timeout", Type: "DURATION"},
+	}); err != nil {
+		return err
+	}
+	v.Name = ctx.StringFlag("name")
+	v.Size = ctx.IntFlag("size")
+	v.PriceLimit = ctx.FloatFlag("price-limit")
+	v.DryRun = ctx.HasFlag("dry-run")
+	v.Backup = ctx.BoolFlag("backup")
+	v.Timeout = ctx.DurationFlag("timeout")
+	v.Args = ctx.GetArgs()
+	return nil
+}
+
+// ServerList contains values of the flags and arguments of 'server list' command
+type ServerList struct {
+	// Args are arguments of the command
+	Args []string
+}
+
+// Bind fills fields with values of the flags and arguments of the command under execution in `ctx`
+// It returns error if the command doesn't match the declaration the code was generated from
+func (v *ServerList) Bind(ctx common.Runtime) error {
+	if err := gen.Verify(ctx, []string{"server", "list"}, []gen.Flag{}); err != nil {
+		return err
+	}
+	v.Args = ctx.GetArgs()
+	return nil
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+	"github.com/pavelmemory/stalk/spec"
+)
+
+func TestServerCreate_Bind(t *testing.T) {
+	var global Global
+	var create ServerCreate
+	wf, err := spec.Load(filepath.Join("..", "..", "testdata", "app.yaml"), spec.Registry{
+		"create": func(ctx common.Runtime) error {
+			if err := global.Bind(ctx); err != nil {
+				return err
+			}
+			return create.Bind(ctx)
+		},
+		"list": func(ctx common.Runtime) error { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf.WithIO(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+
+	if err := wf.Run([]string{"-v", "server", "create", "-n", "web", "--dry-run", "--size", "3", "first", "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedGlobal := Global{Verbose: true, Output: "table"}
+	if !reflect.DeepEqual(expectedGlobal, global) {
+		t.Error("\nexpected:\n", expectedGlobal, "\nactual:\n", global)
+	}
+	expected := ServerCreate{Name: "web", Size: 3, PriceLimit: 0.5, DryRun: true, Backup: true, Timeout: time.Minute, Args: []string{"first", "second"}}
+	if !reflect.DeepEqual(expected, create) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", create)
+	}
+}
+
+func TestServerCreate_Bind_Mismatch(t *testing.T) {
+	var create ServerCreate
+	bind := func(ctx common.Runtime) error { return create.Bind(ctx) }
+
+	for index, scenario := range []struct {
+		parent   string
+		cmd      common.CommandDeclaration
+		args     []string
+		expected string
+	}{
+		/*1*/ {"server", command.New("create").WithFlags(flag.String("name"), flag.Int("size"), flag.Float("price-limit"), flag.Signal("dry-run"), flag.Bool("backup"), flag.Duration("timeout")).WithAction(bind),
+			[]string{"server", "create"}, ""},
+		/*2*/ {"server", command.New("create").WithFlags(flag.String("title"), flag.String("size"), flag.Float("price-limit"), flag.Signal("dry-run"), flag.Bool("backup")).WithAction(bind),
+			[]string{"server", "create"}, "binding doesn't match declaration: command 'server create': flag '--name' is not declared, flag '--size' is declared as STRING instead of INT, flag '--timeout' is not declared, flag '--title' is not bound"},
+		/*3*/ {"server", command.New("delete").WithAction(bind),
+			[]string{"server", "delete"}, "binding doesn't match declaration: binding of command 'server create' is used with command 'server delete'"},
+		/*4*/ {"other", command.New("create").WithFlags(flag.String("name"), flag.Int("size"), flag.Float("price-limit"), flag.Signal("dry-run"), flag.Bool("backup"), flag.Duration("timeout")).WithAction(bind),
+			[]string{"other", "create"}, "binding doesn't match declaration: binding of command 'server create' is used with command 'other create'"},
+	} {
+		wf := stalk.New().WithIO(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{}).WithCommands(command.New(scenario.parent).WithSubCommands(scenario.cmd))
+		err := wf.Run(scenario.args)
+		actual := ""
+		if err != nil {
+			actual = err.Error()
+		}
+		if scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestGlobal_Bind_Mismatch(t *testing.T) {
+	var global Global
+	bind := func(ctx common.Runtime) error { return global.Bind(ctx) }
+
+	for index, scenario := range []struct {
+		workflow stalk.Workflow
+		expected string
+	}{
+		/*1*/ {stalk.New().WithGlobalFlags(flag.Signal("verbose")), ""},
+		/*2*/ {stalk.New().WithGlobalFlags(flag.Bool("verbose"), flag.String("region")),
+			"binding doesn't match declaration: global flags: flag '--verbose' is declared as BOOL instead of signal, flag '--region' is not bound"},
+		/*3*/ {stalk.New().WithGlobalFlags(flag.Signal("debug")).WithOutputFlag(nil),
+			"binding doesn't match declaration: global flags: flag '--verbose' is not declared, flag '--output' is not declared, flag '--debug' is not bound"},
+	} {
+		wf := scenario.workflow.WithIO(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{}).WithCommands(command.New("list").WithAction(bind))
+		err := wf.Run([]string{"list"})
+		actual := ""
+		if err != nil {
+			actual = err.Error()
+		}
+		if scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+// Package help renders usage information of workflow commands and flags
+package help
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/internal/term"
+)
+
+const (
+	// DefaultWidth is used if width was not set, `COLUMNS` environment variable is not set
+	// and output is not a terminal
+	DefaultWidth = 80
+	// MinDescriptionWidth is a minimal width of descriptions column
+	// If there is not enough space for it descriptions are printed under the flag or command
+	MinDescriptionWidth = 20
+	// ColumnsEnv is a name of environment variable that overrides detected terminal width
+	ColumnsEnv = "COLUMNS"
+
+	indent       = 2
+	columnsGap   = 2
+	narrowIndent = 6
+	usagePrefix  = "Usage: "
+)
+
+// Usage contains everything needed to render help for the application or one of its commands
+type Usage struct {
+	// Name is a name of the application
+	Name string
+	// Description is a description of the application
+	Description string
+	// GlobalFlags are flags available to each command
+	GlobalFlags []common.Flag
+	// Commands are top-level commands of the application
+	Commands []common.CommandDeclaration
+	// Plugins are commands provided by external executables, they are listed for the application only
+	Plugins []common.CommandDeclaration
+	// Path is a chain of commands help requested for, empty for the application itself
+	Path []common.CommandDeclaration
+}
+
+// Printer writes help information
+type Printer interface {
+	// WithWidth sets maximum width of the line, if 0 width is detected automatically
+	WithWidth(width int) Printer
+	// GetWidth returns width used for formatting
+	// It is a declared width, `COLUMNS` environment variable value, terminal width or `DefaultWidth`
+	GetWidth() int
+	// Print writes help information about command or application described by `usage`
+	Print(usage Usage) error
+}
+
+// New creates printer that writes help information into `out`
+func New(out io.Writer) Printer {
+	return &printer{out: out}
+}
+
+var _ Printer = (*printer)(nil)
+
+type printer struct {
+	out   io.Writer
+	width int
+}
+
+func (p *printer) WithWidth(width int) Printer {
+	p.width = width
+	return p
+}
+
+func (p *printer) GetWidth() int {
+	if p.width > 0 {
+		return p.width
+	}
+	if columns, err := strconv.Atoi(os.Getenv(ColumnsEnv)); err == nil && columns > 0 {
+		return columns
+	}
+	if width := term.StreamWidth(p.out); width > 0 {
+		return width
+	}
+	return DefaultWidth
+}
+
+func (p *printer) Print(usage Usage) error {
+	width := p.GetWidth()
+	buf := bufio.NewWriter(p.out)
+
+	for _, line := range WrapIndent(usagePrefix+Synopsis(usage), width, utf8.RuneCountInString(usagePrefix)) {
+		buf.WriteString(line + "\n")
+	}
+
+	description := usage.Description
+	commands := common.VisibleCommands(usage.Commands)
+	var flags []common.Flag
+	if len(usage.Path) != 0 {
+		cmd := usage.Path[len(usage.Path)-1]
+		description = cmd.GetDeclaredDescription()
+		commands = common.VisibleCommands(cmd.GetDeclaredSubCommands())
+		flags = common.VisibleFlags(cmd.GetDeclaredFlags())
+	}
+
+	if description != "" {
+		buf.WriteString("\n")
+		for _, line := range Wrap(description, width) {
+			buf.WriteString(line + "\n")
+		}
+	}
+
+	var sections []section
+	if len(commands) != 0 {
+		sections = append(sections, section{title: "Commands:", rows: commandRows(commands)})
+	}
+	if len(usage.Path) == 0 && len(usage.Plugins) != 0 {
+		sections = append(sections, section{title: "Plugins:", rows: commandRows(usage.Plugins)})
+	}
+	if len(flags) != 0 {
+		sections = append(sections, section{title: "Flags:", rows: flagRows(flags)})
+	}
+	if globalFlags := common.VisibleFlags(usage.GlobalFlags); len(globalFlags) != 0 {
+		sections = append(sections, section{title: "Global flags:", rows: flagRows(globalFlags)})
+	}
+
+	leftWidth := 0
+	for _, s := range sections {
+		for _, r := range s.rows {
+			if l := utf8.RuneCountInString(r.left); l > leftWidth {
+				leftWidth = l
+			}
+		}
+	}
+
+	for _, s := range sections {
+		buf.WriteString("\n" + s.title + "\n")
+		for _, r := range s.rows {
+			writeRow(buf, r, leftWidth, width)
+		}
+	}
+
+	if len(usage.Path) != 0 {
+		writeExamples(buf, usage.Name, usage.Path[len(usage.Path)-1].GetDeclaredExamples(), width)
+	}
+	return buf.Flush()
+}
+
+// Wrap splits `text` into lines not longer than `width`
+// Words longer than `width` are placed on separate lines as is
+// New line characters in `text` are preserved as hard line breaks
+func Wrap(text string, width int) []string {
+	return WrapIndent(text, width, 0)
+}
+
+// WrapIndent splits `text` into lines not longer than `width`
+// All lines except the first one are prefixed with `hanging` spaces
+func WrapIndent(text string, width, hanging int) []string {
+	var lines []string
+	prefix := ""
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := prefix
+		lineLen := utf8.RuneCountInString(prefix)
+		empty := true
+		for _, word := range strings.Fields(paragraph) {
+			wordLen := utf8.RuneCountInString(word)
+			if !empty && width > 0 && lineLen+1+wordLen > width {
+				lines = append(lines, line)
+				prefix = strings.Repeat(" ", hanging)
+				line, lineLen, empty = prefix, hanging, true
+			}
+			if !empty {
+				line += " "
+				lineLen++
+			}
+			line += word
+			lineLen += wordLen
+			empty = false
+		}
+		lines = append(lines, strings.TrimRight(line, " "))
+		prefix = strings.Repeat(" ", hanging)
+	}
+	return lines
+}
+
+// GlobalFlags returns not hidden `declared` global flags extended with `builtin` flags, e.g. help flag
+// Built-in flag is not added if it is 'nil' or shadowed by declared flag with the same name or shortcut
+func GlobalFlags(declared []common.Flag, builtin ...common.Flag) []common.Flag {
+	visible := common.VisibleFlags(declared)
+	for _, flag := range builtin {
+		if flag != nil && !common.IsShadowed(flag, declared) {
+			visible = append(visible, flag)
+		}
+	}
+	return visible
+}
+
+type row struct {
+	left        string
+	description string
+}
+
+type section struct {
+	title string
+	rows  []row
+}
+
+func commandRows(commands []common.CommandDeclaration) []row {
+	var rows []row
+	for _, cmd := range commands {
+		rows = append(rows, row{left: cmd.GetName(), description: withDeprecation(cmd.GetDeclaredDescription(), cmd.GetDeclaredDeprecation())})
+	}
+	return rows
+}
+
+func flagRows(flags []common.Flag) []row {
+	var rows []row
+	for _, f := range flags {
+		description := withDeprecation(withEnv(withSensitivity(f.GetDeclaredDescription(), f), f), f.GetDeclaredDeprecation())
+		rows = append(rows, row{left: f.String(), description: description})
+	}
+	return rows
+}
+
+// withSensitivity appends notice about alternative sources of the value of sensitive flag
+func withSensitivity(description string, f common.Flag) string {
+	if !f.IsDeclaredSensitive() || f.IsDeclaredSignal() {
+		return description
+	}
+	notice := "(secret, read from file with '--" + f.GetName() + common.FileFlagSuffix + "' or from stdin with '" + common.StdinValue + "')"
+	if description == "" {
+		return notice
+	}
+	return description + " " + notice
+}
+
+// withEnv appends name of the environment variable used as a value of the flag
+func withEnv(description string, f common.Flag) string {
+	if f.GetDeclaredEnv() == "" {
+		return description
+	}
+	notice := "(env: " + f.GetDeclaredEnv() + ")"
+	if description == "" {
+		return notice
+	}
+	return description + " " + notice
+}
+
+// withDeprecation appends deprecation notice to the description
+func withDeprecation(description string, deprecation *common.Deprecation) string {
+	if deprecation == nil {
+		return description
+	}
+	notice := "(deprecated"
+	if deprecation.Replacement != "" {
+		notice += ", use '" + deprecation.Replacement + "' instead"
+	}
+	notice += ")"
+	if description == "" {
+		return notice
+	}
+	return description + " " + notice
+}
+
+func writeRow(buf *bufio.Writer, r row, leftWidth, width int) {
+	left := strings.Repeat(" ", indent) + r.left
+	if r.description == "" {
+		buf.WriteString(left + "\n")
+		return
+	}
+
+	descriptionStart := indent + leftWidth + columnsGap
+	if width-descriptionStart < MinDescriptionWidth {
+		// narrow terminal: description goes under the flag or command
+		buf.WriteString(left + "\n")
+		for _, line := range Wrap(r.description, width-narrowIndent) {
+			buf.WriteString(strings.Repeat(" ", narrowIndent) + line + "\n")
+		}
+		return
+	}
+
+	padding := strings.Repeat(" ", descriptionStart-utf8.RuneCountInString(left))
+	for i, line := range Wrap(r.description, width-descriptionStart) {
+		if i == 0 {
+			buf.WriteString(left + padding + line + "\n")
+			continue
+		}
+		buf.WriteString(strings.Repeat(" ", descriptionStart) + line + "\n")
+	}
+}
+
+func writeExamples(buf *bufio.Writer, appName string, examples []common.Example, width int) {
+	if len(examples) == 0 {
+		return
+	}
+	buf.WriteString("\nExamples:\n")
+	for i, example := range examples {
+		if i != 0 {
+			buf.WriteString("\n")
+		}
+		if example.Description != "" {
+			for _, line := range Wrap(example.Description, width-indent) {
+				buf.WriteString(strings.Repeat(" ", indent) + line + "\n")
+			}
+		}
+		buf.WriteString(strings.Repeat(" ", 2*indent) + "$ " + appName + " " + example.CommandLine + "\n")
+	}
+}
+
+// Synopsis returns one line summary of how command or application described by `usage` should be called
+func Synopsis(usage Usage) string {
+	parts := []string{usage.Name}
+	if len(usage.GlobalFlags) != 0 {
+		parts = append(parts, "[global flags]")
+	}
+	if len(usage.Path) == 0 {
+		if len(common.VisibleCommands(usage.Commands)) != 0 {
+			parts = append(parts, "<command>")
+		}
+		return strings.Join(parts, " ")
+	}
+
+	for _, cmd := range usage.Path {
+		parts = append(parts, cmd.GetName())
+	}
+	cmd := usage.Path[len(usage.Path)-1]
+	if len(common.VisibleFlags(cmd.GetDeclaredFlags())) != 0 {
+		parts = append(parts, "[flags]")
+	}
+	subCommands := common.VisibleCommands(cmd.GetDeclaredSubCommands())
+	switch {
+	case len(subCommands) != 0 && cmd.GetDeclaredAction() != nil:
+		parts = append(parts, "[command]")
+	case len(subCommands) != 0:
+		parts = append(parts, "<command>")
+	default:
+		parts = append(parts, "[args...]")
+	}
+	return strings.Join(parts, " ")
+}
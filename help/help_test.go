@@ -0,0 +1,133 @@
+package help
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestWrap(t *testing.T) {
+	for index, scenario := range []struct {
+		text     string
+		width    int
+		expected []string
+	}{
+		/*1*/ {"short", 10, []string{"short"}},
+		/*2*/ {"one two three", 7, []string{"one two", "three"}},
+		/*3*/ {"verylongword fits", 5, []string{"verylongword", "fits"}},
+		/*4*/ {"first\nsecond line", 6, []string{"first", "second", "line"}},
+		/*5*/ {"no width limit at all", 0, []string{"no width limit at all"}},
+		/*6*/ {"", 10, []string{""}},
+	} {
+		actual := Wrap(scenario.text, scenario.width)
+		if !reflect.DeepEqual(scenario.expected, actual) {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestWrapIndent(t *testing.T) {
+	expected := []string{"one two", "  three", "  four"}
+	actual := WrapIndent("one two three four", 7, 2)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+}
+
+func TestPrinter_GetWidth(t *testing.T) {
+	t.Setenv(ColumnsEnv, "")
+	if actual := New(&bytes.Buffer{}).GetWidth(); actual != DefaultWidth {
+		t.Error("non-terminal output expected to use default width, got:", actual)
+	}
+
+	t.Setenv(ColumnsEnv, "120")
+	if actual := New(&bytes.Buffer{}).GetWidth(); actual != 120 {
+		t.Error("COLUMNS expected to override width, got:", actual)
+	}
+	if actual := New(&bytes.Buffer{}).WithWidth(50).GetWidth(); actual != 50 {
+		t.Error("declared width expected to take precedence, got:", actual)
+	}
+}
+
+func TestPrinter_Print(t *testing.T) {
+	create := command.New("create").
+		WithDescription("creates new resource with provided name in the selected region").
+		WithFlags(
+			flag.String("name").WithShortcut('n').Required(true).WithDescription("name of the resource"),
+			flag.String("secret-option").Hidden()).
+		WithExample("create resource named test", "aws create -n test").
+		WithAction(func(ctx common.Runtime) error { return nil })
+	aws := command.New("aws").
+		Deprecated("", "cloud").
+		WithDescription("manages aws resources").
+		WithSubCommands(create, command.New("internal").Hidden().WithAction(func(ctx common.Runtime) error { return nil }))
+	usage := Usage{
+		Name: "app",
+		GlobalFlags: []common.Flag{
+			flag.Signal("verbose").WithShortcut('v').WithDescription("verbose output"),
+			flag.Signal("quiet").Hidden()},
+		Commands: []common.CommandDeclaration{aws},
+	}
+
+	for index, scenario := range []struct {
+		width    int
+		path     []common.CommandDeclaration
+		expected string
+	}{
+		/*1*/ {80, nil, `Usage: app [global flags] <command>
+
+Commands:
+  aws              manages aws resources (deprecated, use 'cloud' instead)
+
+Global flags:
+  [--verbose|-v]?  verbose output
+`},
+		/*2*/ {50, []common.CommandDeclaration{aws, create}, `Usage: app [global flags] aws create [flags]
+       [args...]
+
+creates new resource with provided name in the
+selected region
+
+Flags:
+  [--name|-n] [STRING]  name of the resource
+
+Global flags:
+  [--verbose|-v]?       verbose output
+
+Examples:
+  create resource named test
+    $ app aws create -n test
+`},
+		/*3*/ {40, []common.CommandDeclaration{aws, create}, `Usage: app [global flags] aws create
+       [flags] [args...]
+
+creates new resource with provided name
+in the selected region
+
+Flags:
+  [--name|-n] [STRING]
+      name of the resource
+
+Global flags:
+  [--verbose|-v]?
+      verbose output
+
+Examples:
+  create resource named test
+    $ app aws create -n test
+`},
+	} {
+		buf := &bytes.Buffer{}
+		usage.Path = scenario.path
+		if err := New(buf).WithWidth(scenario.width).Print(usage); err != nil {
+			t.Fatal(err)
+		}
+		if actual := buf.String(); scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
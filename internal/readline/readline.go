@@ -0,0 +1,256 @@
+// Package readline reads lines of user input with history and completion
+package readline
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pavelmemory/stalk/internal/term"
+)
+
+// ErrInterrupted is returned if user discarded the line with Ctrl-C
+var ErrInterrupted = errors.New("interrupted")
+
+// Completer returns candidates for the word `partial` that follows already typed text `head`
+type Completer func(head, partial string) []string
+
+// Editor reads lines from the input
+// If input is a terminal it is switched into raw mode while line is edited:
+// arrows move cursor and navigate history, tab completes the word
+type Editor struct {
+	in       io.Reader
+	reader   *bufio.Reader
+	out      io.Writer
+	complete Completer
+	history  []string
+	raw      bool
+}
+
+// New creates editor that reads lines from `in` and echoes them into `out` if `in` is a terminal
+func New(in io.Reader, out io.Writer, complete Completer) *Editor {
+	return &Editor{
+		in:       in,
+		reader:   bufio.NewReader(in),
+		out:      out,
+		complete: complete,
+		raw:      term.IsTerminalStream(in),
+	}
+}
+
+// IsInteractive returns `true` if input is a terminal
+func (e *Editor) IsInteractive() bool {
+	return e.raw
+}
+
+// History returns lines read so far, empty lines are not included
+func (e *Editor) History() []string {
+	return e.history
+}
+
+// ReadLine reads next line, `prompt` is printed only if input is a terminal
+// It returns `io.EOF` when input is over and `ErrInterrupted` when line was discarded
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if !e.raw {
+		line, err := e.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		e.addHistory(line)
+		return line, nil
+	}
+
+	if fd, ok := term.FileDescriptor(e.in); ok {
+		restore, err := term.MakeRaw(fd)
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+	line, err := e.edit(prompt)
+	if err != nil {
+		return "", err
+	}
+	e.addHistory(line)
+	return line, nil
+}
+
+func (e *Editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(e.history) != 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+// state is a line under editing
+type state struct {
+	prompt string
+	line   []rune
+	pos    int
+}
+
+// edit processes key presses until the line is submitted
+func (e *Editor) edit(prompt string) (string, error) {
+	s := &state{prompt: prompt}
+	// position in the history, equal to its length for the new line
+	historyPos := len(e.history)
+	draft := ""
+	e.redraw(s)
+
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			return string(s.line), nil
+		case 3: // Ctrl-C
+			io.WriteString(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case 4: // Ctrl-D
+			if len(s.line) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			s.delete()
+		case 1: // Ctrl-A
+			s.pos = 0
+		case 5: // Ctrl-E
+			s.pos = len(s.line)
+		case 21: // Ctrl-U
+			s.line, s.pos = s.line[s.pos:], 0
+		case 127, 8: // Backspace
+			if s.pos > 0 {
+				s.pos--
+				s.delete()
+			}
+		case '\t':
+			e.completeWord(s)
+		case 27: // escape sequence
+			switch e.escape() {
+			case "[A":
+				if historyPos > 0 {
+					if historyPos == len(e.history) {
+						draft = string(s.line)
+					}
+					historyPos--
+					s.set(e.history[historyPos])
+				}
+			case "[B":
+				if historyPos < len(e.history) {
+					historyPos++
+					if historyPos == len(e.history) {
+						s.set(draft)
+					} else {
+						s.set(e.history[historyPos])
+					}
+				}
+			case "[C":
+				if s.pos < len(s.line) {
+					s.pos++
+				}
+			case "[D":
+				if s.pos > 0 {
+					s.pos--
+				}
+			case "[H", "[1~":
+				s.pos = 0
+			case "[F", "[4~":
+				s.pos = len(s.line)
+			case "[3~":
+				s.delete()
+			}
+		default:
+			if r >= ' ' && r != utf8.RuneError {
+				s.insert([]rune{r})
+			}
+		}
+		e.redraw(s)
+	}
+}
+
+// escape reads rest of the escape sequence, e.g. '[A' for the up arrow
+func (e *Editor) escape() string {
+	var sequence []byte
+	for {
+		b, err := e.reader.ReadByte()
+		if err != nil {
+			return string(sequence)
+		}
+		sequence = append(sequence, b)
+		// sequence ends with a letter or '~', the first byte is '[' or 'O'
+		if len(sequence) > 1 && (b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b == '~') {
+			sequence[0] = '['
+			return string(sequence)
+		}
+	}
+}
+
+func (e *Editor) completeWord(s *state) {
+	if e.complete == nil {
+		return
+	}
+	head := string(s.line[:s.pos])
+	start := strings.LastIndex(head, " ") + 1
+	partial := head[start:]
+	candidates := e.complete(head[:start], partial)
+
+	switch len(candidates) {
+	case 0:
+		return
+	case 1:
+		s.insert([]rune(strings.TrimPrefix(candidates[0], partial) + " "))
+		return
+	}
+
+	prefix := commonPrefix(candidates)
+	if len(prefix) > len(partial) {
+		s.insert([]rune(strings.TrimPrefix(prefix, partial)))
+		return
+	}
+	io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+}
+
+func (e *Editor) redraw(s *state) {
+	buf := "\r" + s.prompt + string(s.line) + "\x1b[K"
+	if back := len(s.line) - s.pos; back > 0 {
+		buf += "\x1b[" + strconv.Itoa(back) + "D"
+	}
+	io.WriteString(e.out, buf)
+}
+
+func (s *state) insert(runes []rune) {
+	line := append(append(append([]rune(nil), s.line[:s.pos]...), runes...), s.line[s.pos:]...)
+	s.line, s.pos = line, s.pos+len(runes)
+}
+
+func (s *state) delete() {
+	if s.pos < len(s.line) {
+		s.line = append(s.line[:s.pos], s.line[s.pos+1:]...)
+	}
+}
+
+func (s *state) set(line string) {
+	s.line = []rune(line)
+	s.pos = len(s.line)
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,73 @@
+package readline
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEditor_ReadLine(t *testing.T) {
+	editor := New(strings.NewReader("first\n\nsecond\r\nlast"), &bytes.Buffer{}, nil)
+	var lines []string
+	for {
+		line, err := editor.ReadLine("> ")
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if expected := []string{"first", "", "second", "last"}; !reflect.DeepEqual(expected, lines) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", lines)
+	}
+	if expected := []string{"first", "second", "last"}; !reflect.DeepEqual(expected, editor.History()) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", editor.History())
+	}
+}
+
+func TestEditor_Edit(t *testing.T) {
+	complete := func(head, partial string) []string {
+		var candidates []string
+		for _, candidate := range []string{"create", "copy", "delete"} {
+			if strings.HasPrefix(candidate, partial) {
+				candidates = append(candidates, candidate)
+			}
+		}
+		return candidates
+	}
+
+	for index, scenario := range []struct {
+		keys     string
+		history  []string
+		expected string
+		err      error
+	}{
+		/*1*/ {"hello\r", nil, "hello", nil},
+		/*2*/ {"helo\x1b[Dl\r", nil, "hello", nil},
+		/*3*/ {"hello\x7f\x7f\r", nil, "hel", nil},
+		/*4*/ {"world\x01hello \r", nil, "hello world", nil},
+		/*5*/ {"\x1b[A\x1b[A\r", []string{"one", "two"}, "one", nil},
+		/*6*/ {"draft\x1b[A\x1b[B\r", []string{"one"}, "draft", nil},
+		/*7*/ {"d\t\r", nil, "delete ", nil},
+		/*8*/ {"c\to\t\r", nil, "copy ", nil},
+		/*9*/ {"abc\x15x\r", nil, "x", nil},
+		/*10*/ {"abc\x03", nil, "", ErrInterrupted},
+		/*11*/ {"\x04", nil, "", io.EOF},
+		/*12*/ {"ab\x1b[D\x1b[3~\r", nil, "a", nil},
+	} {
+		editor := New(strings.NewReader(scenario.keys), &bytes.Buffer{}, complete)
+		editor.raw = true
+		editor.history = scenario.history
+		actual, err := editor.ReadLine("> ")
+		if err != scenario.err {
+			t.Error("index:", index+1, "unexpected error:", err)
+		}
+		if scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package term provides minimal set of terminal related operations used by stalk packages
+package term
+
+import (
+	"io"
+	"os"
+)
+
+// FileDescriptor returns file descriptor of provided stream if it is backed by a file
+func FileDescriptor(stream interface{}) (uintptr, bool) {
+	if f, ok := stream.(*os.File); ok && f != nil {
+		return f.Fd(), true
+	}
+	return 0, false
+}
+
+// IsTerminalStream returns `true` if provided stream is a file that refers to a terminal
+func IsTerminalStream(stream interface{}) bool {
+	fd, ok := FileDescriptor(stream)
+	return ok && IsTerminal(fd)
+}
+
+// StreamWidth returns columns count of terminal referenced by provided stream or 0 if it can't be detected
+func StreamWidth(stream io.Writer) int {
+	fd, ok := FileDescriptor(stream)
+	if !ok || !IsTerminal(fd) {
+		return 0
+	}
+	return Width(fd)
+}
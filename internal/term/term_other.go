@@ -0,0 +1,29 @@
+//go:build !linux && !darwin
+
+package term
+
+import "errors"
+
+// IsTerminal returns `true` if provided file descriptor refers to a terminal
+// Terminal detection is not supported on this platform, so it always returns `false`
+func IsTerminal(fd uintptr) bool {
+	return false
+}
+
+// Width returns columns count of terminal referenced by provided file descriptor or 0 if it can't be detected
+// Width detection is not supported on this platform, so it always returns 0
+func Width(fd uintptr) int {
+	return 0
+}
+
+// DisableEcho turns off echo of typed characters on terminal referenced by provided file descriptor
+// Terminal control is not supported on this platform, so it always returns an error
+func DisableEcho(fd uintptr) (func() error, error) {
+	return nil, errors.New("terminal control is not supported on this platform")
+}
+
+// MakeRaw puts terminal referenced by provided file descriptor into raw mode
+// Terminal control is not supported on this platform, so it always returns an error
+func MakeRaw(fd uintptr) (func() error, error) {
+	return nil, errors.New("terminal control is not supported on this platform")
+}
@@ -0,0 +1,87 @@
+//go:build linux || darwin
+
+package term
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+type winsize struct {
+	rows    uint16
+	columns uint16
+	xPixels uint16
+	yPixels uint16
+}
+
+// IsTerminal returns `true` if provided file descriptor refers to a terminal
+func IsTerminal(fd uintptr) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlReadTermios, uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
+
+// Width returns columns count of terminal referenced by provided file descriptor or 0 if it can't be detected
+func Width(fd uintptr) int {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0
+	}
+	return int(ws.columns)
+}
+
+// DisableEcho turns off echo of typed characters on terminal referenced by provided file descriptor
+// Returned function restores previous state of the terminal
+func DisableEcho(fd uintptr) (func() error, error) {
+	termios, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	previous := *termios
+	termios.Lflag &^= syscall.ECHO
+	if err := setTermios(fd, termios); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return setTermios(fd, &previous)
+	}, nil
+}
+
+// MakeRaw puts terminal referenced by provided file descriptor into raw mode:
+// input is available byte by byte without echo and signals are not generated by control characters
+// Returned function restores previous state of the terminal
+func MakeRaw(fd uintptr) (func() error, error) {
+	termios, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	previous := *termios
+	termios.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	termios.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	termios.Cflag &^= syscall.CSIZE | syscall.PARENB
+	termios.Cflag |= syscall.CS8
+	termios.Cc[syscall.VMIN] = 1
+	termios.Cc[syscall.VTIME] = 0
+	if err := setTermios(fd, termios); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return setTermios(fd, &previous)
+	}, nil
+}
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	termios := &syscall.Termios{}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlReadTermios, uintptr(unsafe.Pointer(termios))); errno != 0 {
+		return nil, errno
+	}
+	return termios, nil
+}
+
+func setTermios(fd uintptr, termios *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlWriteTermios, uintptr(unsafe.Pointer(termios))); errno != 0 {
+		return errno
+	}
+	return nil
+}
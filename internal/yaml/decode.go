@@ -0,0 +1,532 @@
+package yaml
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal parses YAML document into generic values:
+// mappings become 'map[string]interface{}', sequences '[]interface{}',
+// scalars 'string', 'bool', 'int64', 'float64' or 'nil'
+// Block and flow collections, plain and quoted scalars, literal ('|') and folded ('>') block scalars are supported,
+// anchors, tags and multiple documents are not
+func Unmarshal(data []byte) (interface{}, error) {
+	p := &parser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		l, err := newLine(i+1, raw)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && l.text == "---" {
+			continue
+		}
+		p.lines = append(p.lines, l)
+	}
+
+	p.skipBlank()
+	if p.done() {
+		return nil, nil
+	}
+	value, err := p.block(p.current().indent)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if !p.done() {
+		return nil, p.errorf("unexpected content: " + p.current().text)
+	}
+	return value, nil
+}
+
+// line of the document
+type line struct {
+	number int
+	indent int
+	// text is a content of the line without indentation, trailing spaces and comment
+	text string
+	raw  string
+}
+
+func newLine(number int, raw string) (line, error) {
+	trimmed := strings.TrimLeft(raw, " ")
+	l := line{number: number, indent: len(raw) - len(trimmed), raw: raw}
+	if strings.HasPrefix(trimmed, "\t") {
+		return l, lineError(number, "tabs can't be used for indentation")
+	}
+	l.text = strings.TrimRight(stripComment(trimmed), " \t")
+	return l, nil
+}
+
+// stripComment removes comment that starts with '#' at the beginning or after whitespace outside of quotes
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" [{,:", s[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func lineError(number int, msg string) error {
+	return errors.New("yaml: line " + strconv.Itoa(number) + ": " + msg)
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.lines)
+}
+
+func (p *parser) current() line {
+	return p.lines[p.pos]
+}
+
+func (p *parser) errorf(msg string) error {
+	if p.done() {
+		return errors.New("yaml: " + msg)
+	}
+	return lineError(p.current().number, msg)
+}
+
+// skipBlank moves to the next line with content
+func (p *parser) skipBlank() {
+	for !p.done() && p.current().text == "" {
+		p.pos++
+	}
+}
+
+// block parses collection or scalar which lines are indented by `indent`
+func (p *parser) block(indent int) (interface{}, error) {
+	l := p.current()
+	switch {
+	case isSequenceItem(l.text):
+		return p.sequence(indent)
+	case mappingKeyEnd(l.text) >= 0:
+		return p.mapping(indent)
+	default:
+		value, err := p.inline(l.text)
+		p.pos++
+		return value, err
+	}
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// mappingKeyEnd returns index of ':' that ends the key of mapping entry in `text` or -1 if it is not an entry
+func mappingKeyEnd(text string) int {
+	if text == "" || strings.IndexByte("[{", text[0]) >= 0 {
+		return -1
+	}
+	start := 0
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text, 0)
+		if end < 0 {
+			return -1
+		}
+		start = end + 1
+	}
+	for i := start; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return i
+		}
+	}
+	return -1
+}
+
+// closingQuote returns index of the quote that closes quoted scalar started at `start`
+func closingQuote(text string, start int) int {
+	quote := text[start]
+	for i := start + 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *parser) sequence(indent int) (interface{}, error) {
+	items := []interface{}{}
+	for p.skipBlank(); !p.done(); p.skipBlank() {
+		l := p.current()
+		if l.indent < indent {
+			break
+		}
+		if l.indent == indent && !isSequenceItem(l.text) {
+			// sequence is a value of the mapping entry placed at the same indentation
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected content in sequence: " + l.text)
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			item, err := p.nested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+		// content after '- ' is parsed as a block indented by its offset, e.g. '- name: x' followed by '  flags: ...'
+		p.lines[p.pos].indent = indent + len(l.text) - len(rest)
+		p.lines[p.pos].text = rest
+		item, err := p.block(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (p *parser) mapping(indent int) (interface{}, error) {
+	entries := map[string]interface{}{}
+	for p.skipBlank(); !p.done(); p.skipBlank() {
+		l := p.current()
+		if l.indent < indent {
+			break
+		}
+		end := mappingKeyEnd(l.text)
+		if l.indent > indent || end < 0 {
+			return nil, p.errorf("unexpected content in mapping: " + l.text)
+		}
+
+		key, err := p.scalar(strings.TrimSpace(l.text[:end]))
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			name = l.text[:end]
+		}
+		if _, found := entries[name]; found {
+			return nil, p.errorf("duplicate key: " + name)
+		}
+
+		rest := strings.TrimSpace(l.text[end+1:])
+		var value interface{}
+		switch {
+		case rest == "":
+			p.pos++
+			value, err = p.nested(indent, true)
+		case rest[0] == '|' || rest[0] == '>':
+			value, err = p.blockScalar(indent, rest)
+		default:
+			value, err = p.inline(rest)
+			p.pos++
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries[name] = value
+	}
+	return entries, nil
+}
+
+// nested parses value placed on the lines following the key or sequence item indented by `indent`
+// Sequence that is a value of the mapping entry can be placed at the same indentation as its key
+func (p *parser) nested(indent int, mappingValue bool) (interface{}, error) {
+	p.skipBlank()
+	if p.done() {
+		return nil, nil
+	}
+	l := p.current()
+	if l.indent > indent || mappingValue && l.indent == indent && isSequenceItem(l.text) {
+		return p.block(l.indent)
+	}
+	return nil, nil
+}
+
+// blockScalar reads literal ('|') or folded ('>') scalar from the lines indented deeper than `indent`
+// Current line is the one with the `header`
+func (p *parser) blockScalar(indent int, header string) (interface{}, error) {
+	chomp := ""
+	for _, c := range header[1:] {
+		switch c {
+		case '-', '+':
+			chomp = string(c)
+		default:
+			return nil, p.errorf("unsupported block scalar header: " + header)
+		}
+	}
+
+	start := p.pos + 1
+	end := start // index after the last line with content
+	contentIndent := -1
+	for i := start; i < len(p.lines); i++ {
+		raw := p.lines[i].raw
+		trimmed := strings.TrimLeft(raw, " ")
+		if trimmed == "" {
+			continue
+		}
+		lineIndent := len(raw) - len(trimmed)
+		if lineIndent <= indent {
+			break
+		}
+		if contentIndent < 0 {
+			contentIndent = lineIndent
+		}
+		if lineIndent < contentIndent {
+			return nil, lineError(p.lines[i].number, "insufficient indentation of block scalar")
+		}
+		end = i + 1
+	}
+	trailing := 0
+	for i := end; i < len(p.lines) && strings.TrimSpace(p.lines[i].raw) == ""; i++ {
+		trailing++
+	}
+
+	var content []string
+	for _, l := range p.lines[start:end] {
+		if strings.TrimSpace(l.raw) == "" {
+			content = append(content, "")
+		} else {
+			content = append(content, l.raw[contentIndent:])
+		}
+	}
+	p.pos = end
+
+	var text string
+	if header[0] == '|' {
+		text = strings.Join(content, "\n")
+	} else {
+		text = fold(content)
+	}
+	switch {
+	case len(content) == 0:
+		return "", nil
+	case chomp == "-":
+		return text, nil
+	case chomp == "+":
+		return text + strings.Repeat("\n", trailing+1), nil
+	default:
+		return text + "\n", nil
+	}
+}
+
+// fold joins lines of folded scalar with spaces, empty lines become line breaks
+func fold(lines []string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		switch {
+		case i == 0:
+		case l == "" || lines[i-1] == "":
+			if l == "" {
+				b.WriteString("\n")
+			}
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(l)
+	}
+	return b.String()
+}
+
+// inline parses value written on a single line: flow collection or scalar
+func (p *parser) inline(text string) (interface{}, error) {
+	if text[0] != '[' && text[0] != '{' {
+		return p.scalar(text)
+	}
+	f := &flow{text: text, p: p}
+	value, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	f.space()
+	if f.pos != len(f.text) {
+		return nil, p.errorf("unexpected content after flow collection: " + f.text[f.pos:])
+	}
+	return value, nil
+}
+
+// scalar resolves plain or quoted scalar
+func (p *parser) scalar(text string) (interface{}, error) {
+	if text == "" {
+		return nil, nil
+	}
+	switch text[0] {
+	case '"':
+		if closingQuote(text, 0) != len(text)-1 {
+			return nil, p.errorf("invalid double-quoted scalar: " + text)
+		}
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, p.errorf("invalid double-quoted scalar: " + text)
+		}
+		return value, nil
+	case '\'':
+		if closingQuote(text, 0) != len(text)-1 {
+			return nil, p.errorf("invalid single-quoted scalar: " + text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case '&', '*', '!', '@', '`':
+		return nil, p.errorf("unsupported scalar: " + text)
+	}
+	return resolve(text), nil
+}
+
+// resolve converts plain scalar into the value of the type it represents
+func resolve(text string) interface{} {
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	if i, err := strconv.ParseInt(text, 0, 64); err == nil {
+		return i
+	}
+	if strings.IndexAny(text, "0123456789") >= 0 && !strings.ContainsAny(text, "_xXoObB") {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return text
+}
+
+// flow parses flow collections, e.g. '[a, b]' or '{name: x, type: string}'
+type flow struct {
+	text string
+	pos  int
+	p    *parser
+}
+
+func (f *flow) space() {
+	for f.pos < len(f.text) && f.text[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *flow) value() (interface{}, error) {
+	f.space()
+	if f.pos >= len(f.text) {
+		return nil, f.p.errorf("unexpected end of flow collection")
+	}
+	switch f.text[f.pos] {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		end := closingQuote(f.text, f.pos)
+		if end < 0 {
+			return nil, f.p.errorf("unterminated quoted scalar: " + f.text[f.pos:])
+		}
+		value, err := f.p.scalar(f.text[f.pos : end+1])
+		f.pos = end + 1
+		return value, err
+	}
+	start := f.pos
+	for f.pos < len(f.text) && strings.IndexByte(",]}", f.text[f.pos]) < 0 &&
+		!(f.text[f.pos] == ':' && (f.pos+1 == len(f.text) || strings.IndexByte(" ,]}", f.text[f.pos+1]) >= 0)) {
+		f.pos++
+	}
+	return f.p.scalar(strings.TrimSpace(f.text[start:f.pos]))
+}
+
+func (f *flow) sequence() (interface{}, error) {
+	f.pos++
+	items := []interface{}{}
+	for {
+		f.space()
+		if f.pos < len(f.text) && f.text[f.pos] == ']' {
+			f.pos++
+			return items, nil
+		}
+		item, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *flow) mapping() (interface{}, error) {
+	f.pos++
+	entries := map[string]interface{}{}
+	for {
+		f.space()
+		if f.pos < len(f.text) && f.text[f.pos] == '}' {
+			f.pos++
+			return entries, nil
+		}
+		key, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, f.p.errorf("flow mapping key must be a string")
+		}
+		f.space()
+		var value interface{}
+		if f.pos < len(f.text) && f.text[f.pos] == ':' {
+			f.pos++
+			f.space()
+			if f.pos < len(f.text) && f.text[f.pos] != ',' && f.text[f.pos] != '}' {
+				if value, err = f.value(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if _, found := entries[name]; found {
+			return nil, f.p.errorf("duplicate key: " + name)
+		}
+		entries[name] = value
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes ',' between items of flow collection, closing character is left for the caller
+func (f *flow) separator(closing byte) error {
+	f.space()
+	switch {
+	case f.pos >= len(f.text):
+		return f.p.errorf("unterminated flow collection: " + f.text)
+	case f.text[f.pos] == ',':
+		f.pos++
+	case f.text[f.pos] != closing:
+		return f.p.errorf("expected ',' or '" + string(closing) + "' in flow collection: " + f.text[f.pos:])
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+type m = map[string]interface{}
+type s = []interface{}
+
+func TestUnmarshal(t *testing.T) {
+	for index, scenario := range []struct {
+		document string
+		expected interface{}
+	}{
+		/*1*/ {"", nil},
+		/*2*/ {"plain text # comment", "plain text"},
+		/*3*/ {"---\n12", int64(12)},
+		/*4*/ {"a: 1\nb: 1.5\nc: true\nd: ~\ne: 'it''s'\nf: \"tab\\t#\"\ng: 0x10\nh: 1s\ni:", m{"a": int64(1), "b": 1.5, "c": true, "d": nil, "e": "it's", "f": "tab\t#", "g": int64(16), "h": "1s", "i": nil}},
+		/*5*/ {"- a\n-  b\n- - c\n  - d\n-\n  e", s{"a", "b", s{"c", "d"}, "e"}},
+		/*6*/ {`
+name: app   # name of the application
+commands:
+- name: aws
+  flags:
+    - {name: region, type: string, default: eu}
+    - name: "dry run"
+      shortcut: n
+  commands: []
+- name: azure
+tags: [a, "b, c", [d], {}]
+url: http://example.com/path#anchor
+`, m{
+			"name": "app",
+			"commands": s{
+				m{"name": "aws", "flags": s{m{"name": "region", "type": "string", "default": "eu"}, m{"name": "dry run", "shortcut": "n"}}, "commands": s{}},
+				m{"name": "azure"},
+			},
+			"tags": s{"a", "b, c", s{"d"}, m{}},
+			"url":  "http://example.com/path#anchor",
+		}},
+		/*7*/ {"literal: |\n  first\n\n   second\n\nfolded: >-\n  one\n  two\n\n  three\nkeep: |+\n  text\n\nnext: x\n", m{
+			"literal": "first\n\n second\n",
+			"folded":  "one two\nthree",
+			"keep":    "text\n\n",
+			"next":    "x",
+		}},
+		/*8*/ {"a:\n  b:\n    c: [1, 2]\n  d: e\nf: g", m{"a": m{"b": m{"c": s{int64(1), int64(2)}}, "d": "e"}, "f": "g"}},
+	} {
+		actual, err := Unmarshal([]byte(scenario.document))
+		if err != nil {
+			t.Error("index:", index+1, "unexpected error:", err)
+			continue
+		}
+		if !reflect.DeepEqual(scenario.expected, actual) {
+			t.Errorf("index: %d\nexpected:\n%#v\nactual:\n%#v", index+1, scenario.expected, actual)
+		}
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	for index, scenario := range []struct {
+		document string
+		expected string
+	}{
+		/*1*/ {"a: 1\n\tb: 2", "yaml: line 2: tabs can't be used for indentation"},
+		/*2*/ {"a: 1\na: 2", "yaml: line 2: duplicate key: a"},
+		/*3*/ {"a: 1\n  b: 2", "yaml: line 2: unexpected content in mapping: b: 2"},
+		/*4*/ {"- a\nb: 1", "yaml: line 2: unexpected content: b: 1"},
+		/*5*/ {"a: [1, 2", "yaml: line 1: unterminated flow collection: [1, 2"},
+		/*6*/ {"a: 'open", "yaml: line 1: invalid single-quoted scalar: 'open"},
+		/*7*/ {"a: &anchor x", "yaml: line 1: unsupported scalar: &anchor x"},
+		/*8*/ {"a: {b: 1} c", "yaml: line 1: unexpected content after flow collection: c"},
+	} {
+		_, err := Unmarshal([]byte(scenario.document))
+		if err == nil || err.Error() != scenario.expected {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", err)
+		}
+	}
+}
+
+func TestUnmarshal_Marshal(t *testing.T) {
+	value := m{"name": "yes", "list": s{"a", int64(1), m{"key": "12"}}, "empty": s{}, "text": "line\nbreak"}
+	document, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := Unmarshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(value, actual) {
+		t.Errorf("\nexpected:\n%#v\nactual:\n%#v\ndocument:\n%s", value, actual, document)
+	}
+}
@@ -0,0 +1,274 @@
+// Package yaml implements subset of YAML used by stalk packages
+package yaml
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Marshal returns YAML document representing `value`
+// Maps, slices, arrays, structs and scalar values are supported
+// Struct fields are named by `yaml` tag, `json` tag or field name, `omitempty` option and '-' name are respected
+func Marshal(value interface{}) ([]byte, error) {
+	scalar, block, err := encode(reflect.ValueOf(value))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if block == nil {
+		buf.WriteString(scalar + "\n")
+		return buf.Bytes(), nil
+	}
+	for _, line := range block {
+		buf.WriteString(line + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// encode returns representation of `v` either as a single scalar or as lines of the block
+// Lines of the block are not indented, caller indents them depending on where the block is placed
+func encode(v reflect.Value) (string, []string, error) {
+	if !v.IsValid() {
+		return "null", nil, nil
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "null", nil, nil
+		}
+		if v.CanInterface() {
+			if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+				return encodeText(marshaler)
+			}
+		}
+		v = v.Elem()
+	}
+	if v.CanInterface() {
+		if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+			return encodeText(marshaler)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil, nil
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(v.Float()), nil, nil
+	case reflect.String:
+		return Quote(v.String()), nil, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return "null", nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return Quote(base64.StdEncoding.EncodeToString(v.Bytes())), nil, nil
+		}
+		return encodeSequence(v)
+	case reflect.Array:
+		return encodeSequence(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return "null", nil, nil
+		}
+		return encodeMap(v)
+	case reflect.Struct:
+		return encodeStruct(v)
+	default:
+		return "", nil, fmt.Errorf("yaml: unsupported type %s", v.Type())
+	}
+}
+
+func encodeText(marshaler encoding.TextMarshaler) (string, []string, error) {
+	text, err := marshaler.MarshalText()
+	if err != nil {
+		return "", nil, err
+	}
+	return Quote(string(text)), nil, nil
+}
+
+func encodeSequence(v reflect.Value) (string, []string, error) {
+	if v.Len() == 0 {
+		return "[]", nil, nil
+	}
+	var lines []string
+	for i := 0; i < v.Len(); i++ {
+		scalar, block, err := encode(v.Index(i))
+		if err != nil {
+			return "", nil, err
+		}
+		if block == nil {
+			lines = append(lines, "- "+scalar)
+			continue
+		}
+		for j, line := range block {
+			if j == 0 {
+				lines = append(lines, "- "+line)
+			} else {
+				lines = append(lines, "  "+line)
+			}
+		}
+	}
+	return "", lines, nil
+}
+
+type entry struct {
+	key   string
+	value reflect.Value
+}
+
+func encodeMap(v reflect.Value) (string, []string, error) {
+	var entries []entry
+	for _, key := range v.MapKeys() {
+		entries = append(entries, entry{key: fmt.Sprint(key.Interface()), value: v.MapIndex(key)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return encodeMapping(entries)
+}
+
+func encodeStruct(v reflect.Value) (string, []string, error) {
+	return encodeMapping(structEntries(v))
+}
+
+// structEntries returns exported fields of the struct, fields of embedded structs without name are inlined
+func structEntries(v reflect.Value) []entry {
+	var entries []entry
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, omitEmpty, skip := fieldName(field)
+		if skip {
+			continue
+		}
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			for value.Kind() == reflect.Ptr && !value.IsNil() {
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				entries = append(entries, structEntries(value)...)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if omitEmpty && value.IsZero() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		entries = append(entries, entry{key: name, value: value})
+	}
+	return entries
+}
+
+// fieldName returns name of the field declared by `yaml` or `json` tag
+func fieldName(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag, found := field.Tag.Lookup("yaml")
+	if !found {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty, false
+}
+
+func encodeMapping(entries []entry) (string, []string, error) {
+	if len(entries) == 0 {
+		return "{}", nil, nil
+	}
+	var lines []string
+	for _, e := range entries {
+		scalar, block, err := encode(e.value)
+		if err != nil {
+			return "", nil, err
+		}
+		key := Quote(e.key)
+		if block == nil {
+			lines = append(lines, key+": "+scalar)
+			continue
+		}
+		lines = append(lines, key+":")
+		for _, line := range block {
+			lines = append(lines, "  "+line)
+		}
+	}
+	return "", lines, nil
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+	formatted := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(formatted, ".eEn") {
+		// keeps value a float when read back
+		formatted += ".0"
+	}
+	return formatted
+}
+
+// Quote returns `s` as is if it can be used as plain scalar or as double-quoted scalar otherwise
+func Quote(s string) string {
+	if isPlain(s) {
+		return s
+	}
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	// JSON string is a valid double-quoted YAML scalar, encoding of a string never fails
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+var reserved = map[string]bool{
+	"~": true, "null": true, "true": true, "false": true,
+	"y": true, "n": true, "yes": true, "no": true, "on": true, "off": true,
+}
+
+func isPlain(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || reserved[strings.ToLower(s)] {
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`.+") {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
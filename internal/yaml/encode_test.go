@@ -0,0 +1,72 @@
+package yaml
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type embedded struct {
+	ID int `yaml:"id"`
+}
+
+type document struct {
+	embedded
+	Name     string            `json:"name"`
+	Tags     []string          `yaml:"tags"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+	Children []document        `yaml:"children,omitempty"`
+	Ignored  string            `yaml:"-"`
+}
+
+func TestMarshal(t *testing.T) {
+	for index, scenario := range []struct {
+		value    interface{}
+		expected string
+	}{
+		/*1*/ {nil, "null\n"},
+		/*2*/ {"plain text", "plain text\n"},
+		/*3*/ {"yes", "\"yes\"\n"},
+		/*4*/ {"12", "\"12\"\n"},
+		/*5*/ {"key: value", "\"key: value\"\n"},
+		/*6*/ {"line\nbreak", "\"line\\nbreak\"\n"},
+		/*7*/ {3.0, "3.0\n"},
+		/*8*/ {math.Inf(-1), "-.inf\n"},
+		/*9*/ {[]int{}, "[]\n"},
+		/*10*/ {time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), "2020-01-02T03:04:05Z\n"},
+		/*11*/ {map[string]interface{}{"b": []int{1, 2}, "a": map[string]bool{}}, "a: {}\nb:\n  - 1\n  - 2\n"},
+		/*12*/ {document{
+			embedded: embedded{ID: 1},
+			Name:     "root",
+			Tags:     []string{"-x", "y"},
+			Labels:   map[string]string{"env": "prod"},
+			Children: []document{{Name: "child"}},
+			Ignored:  "ignored",
+		}, `id: 1
+name: root
+tags:
+  - "-x"
+  - "y"
+labels:
+  env: prod
+children:
+  - id: 0
+    name: child
+    tags: null
+`},
+	} {
+		actual, err := Marshal(scenario.value)
+		if err != nil {
+			t.Fatal(index+1, err)
+		}
+		if scenario.expected != string(actual) {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", string(actual))
+		}
+	}
+}
+
+func TestMarshal_Unsupported(t *testing.T) {
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Error("error expected for channel")
+	}
+}
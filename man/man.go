@@ -0,0 +1,297 @@
+// Package man generates roff man pages for the workflow and each of its commands
+package man
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/help"
+)
+
+// DefaultSection is a manual section used if other was not set, it is a section of user commands
+const DefaultSection = 1
+
+// Page is a generated man page
+type Page struct {
+	// Name is a name of the page built from the command path, e.g. 'app-aws-create'
+	Name string
+	// Section is a manual section of the page
+	Section int
+	// Content is a roff source of the page
+	Content []byte
+}
+
+// FileName returns name of the file for the page, e.g. 'app-aws-create.1'
+func (p Page) FileName() string {
+	return p.Name + "." + strconv.Itoa(p.Section)
+}
+
+// Generator produces man pages for the application and each command path of the workflow
+type Generator interface {
+	// WithSection sets manual section of generated pages, `DefaultSection` used if not set
+	WithSection(section int) Generator
+	// WithDate sets date placed into header of generated pages, current month used if not set
+	WithDate(date string) Generator
+	// WithSource sets source of the pages placed into header, usually name and version of the application
+	WithSource(source string) Generator
+	// WithManual sets title of the manual placed into header
+	WithManual(manual string) Generator
+	// Generate returns pages for the application and each command path
+	Generate() []Page
+	// WriteTo writes generated pages into directory `dir`
+	WriteTo(dir string) error
+}
+
+// New creates generator of man pages for the `workflow`
+func New(workflow stalk.Workflow) Generator {
+	return &generator{
+		workflow: workflow,
+		section:  DefaultSection,
+		date:     time.Now().Format("January 2006"),
+	}
+}
+
+var _ Generator = (*generator)(nil)
+
+type generator struct {
+	workflow stalk.Workflow
+	section  int
+	date     string
+	source   string
+	manual   string
+}
+
+func (g *generator) WithSection(section int) Generator {
+	g.section = section
+	return g
+}
+
+func (g *generator) WithDate(date string) Generator {
+	g.date = date
+	return g
+}
+
+func (g *generator) WithSource(source string) Generator {
+	g.source = source
+	return g
+}
+
+func (g *generator) WithManual(manual string) Generator {
+	g.manual = manual
+	return g
+}
+
+func (g *generator) Generate() []Page {
+	pages := []Page{g.page(nil)}
+	return append(pages, g.commandPages(nil, common.VisibleCommands(g.workflow.GetDeclaredCommands()))...)
+}
+
+func (g *generator) WriteTo(dir string) error {
+	for _, page := range g.Generate() {
+		if err := ioutil.WriteFile(filepath.Join(dir, page.FileName()), page.Content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) commandPages(parent []common.CommandDeclaration, commands []common.CommandDeclaration) []Page {
+	var pages []Page
+	for _, cmd := range commands {
+		path := append(append([]common.CommandDeclaration(nil), parent...), cmd)
+		pages = append(pages, g.page(path))
+		pages = append(pages, g.commandPages(path, common.VisibleCommands(cmd.GetDeclaredSubCommands()))...)
+	}
+	return pages
+}
+
+func (g *generator) page(path []common.CommandDeclaration) Page {
+	name := pageName(g.workflow.GetDeclaredName(), path)
+	description := g.workflow.GetDeclaredDescription()
+	commands := common.VisibleCommands(g.workflow.GetDeclaredCommands())
+	var flags []common.Flag
+	if len(path) != 0 {
+		cmd := path[len(path)-1]
+		description = cmd.GetDeclaredDescription()
+		commands = common.VisibleCommands(cmd.GetDeclaredSubCommands())
+		flags = common.VisibleFlags(cmd.GetDeclaredFlags())
+	}
+	globalFlags := help.GlobalFlags(g.workflow.GetDeclaredGlobalFlags(), g.workflow.GetDeclaredOutputFlag(), g.workflow.GetDeclaredScriptFlag(), g.workflow.GetDeclaredHelpFlag())
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(".TH " + quote(strings.ToUpper(name)) + " " + quote(strconv.Itoa(g.section)) + " " +
+		quote(g.date) + " " + quote(g.source) + " " + quote(g.manual) + "\n")
+
+	buf.WriteString(".SH NAME\n")
+	summary := strings.Split(description, "\n")[0]
+	if summary == "" {
+		buf.WriteString(escape(name) + "\n")
+	} else {
+		buf.WriteString(escape(name) + " \\- " + escape(summary) + "\n")
+	}
+
+	buf.WriteString(".SH SYNOPSIS\n")
+	buf.WriteString(synopsis(g.workflow.GetDeclaredName(), globalFlags, commands, path))
+
+	if description != "" {
+		buf.WriteString(".SH DESCRIPTION\n")
+		writeText(buf, description)
+	}
+
+	if len(flags) != 0 || len(globalFlags) != 0 {
+		buf.WriteString(".SH OPTIONS\n")
+		writeFlags(buf, flags)
+		if len(globalFlags) != 0 {
+			if len(flags) != 0 {
+				buf.WriteString(".SS \"Global options\"\n")
+			}
+			writeFlags(buf, globalFlags)
+		}
+	}
+
+	if len(commands) != 0 {
+		buf.WriteString(".SH COMMANDS\n")
+		for _, cmd := range commands {
+			buf.WriteString(".TP\n.B " + escape(cmd.GetName()) + "\n")
+			if cmdDescription := cmd.GetDeclaredDescription(); cmdDescription != "" {
+				writeText(buf, cmdDescription)
+			}
+			childName := pageName(g.workflow.GetDeclaredName(), append(append([]common.CommandDeclaration(nil), path...), cmd))
+			buf.WriteString("See \\fB" + escape(childName) + "\\fR(" + strconv.Itoa(g.section) + ").\n")
+		}
+	}
+
+	if len(path) != 0 {
+		writeExamples(buf, g.workflow.GetDeclaredName(), path[len(path)-1].GetDeclaredExamples())
+	}
+
+	writeEnvironment(buf, append(append([]common.Flag(nil), flags...), globalFlags...))
+
+	buf.WriteString(".SH \"EXIT STATUS\"\n")
+	for _, status := range exitStatuses {
+		buf.WriteString(".TP\n.B " + strconv.Itoa(status.code) + "\n" + status.description + "\n")
+	}
+
+	return Page{Name: name, Section: g.section, Content: buf.Bytes()}
+}
+
+// exitStatuses describes exit codes of the application started with `stalk.Main`
+var exitStatuses = []struct {
+	code        int
+	description string
+}{
+	{stalk.ExitOK, "Command completed successfully."},
+	{stalk.ExitFailure, "Command failed."},
+	{stalk.ExitUsage, "Invalid command line arguments."},
+	{stalk.ExitDeclaration, "Invalid declaration of the application."},
+	{stalk.ExitAborted, "Aborted by the user."},
+	{stalk.ExitTimeout, "Timeout exceeded."},
+	{stalk.ExitInterrupted, "Interrupted by the signal."},
+}
+
+func pageName(appName string, path []common.CommandDeclaration) string {
+	parts := []string{appName}
+	for _, cmd := range path {
+		parts = append(parts, cmd.GetName())
+	}
+	return strings.Join(parts, "-")
+}
+
+func synopsis(appName string, globalFlags []common.Flag, commands []common.CommandDeclaration, path []common.CommandDeclaration) string {
+	parts := []string{"\\fB" + escape(appName) + "\\fR"}
+	if len(globalFlags) != 0 {
+		parts = append(parts, "[\\fIglobal flags\\fR]")
+	}
+	for _, cmd := range path {
+		parts = append(parts, "\\fB"+escape(cmd.GetName())+"\\fR")
+	}
+	if len(path) != 0 {
+		cmd := path[len(path)-1]
+		if len(common.VisibleFlags(cmd.GetDeclaredFlags())) != 0 {
+			parts = append(parts, "[\\fIflags\\fR]")
+		}
+		switch {
+		case len(commands) != 0 && cmd.GetDeclaredAction() != nil:
+			parts = append(parts, "[\\fIcommand\\fR]")
+		case len(commands) != 0:
+			parts = append(parts, "\\fIcommand\\fR")
+		default:
+			parts = append(parts, "[\\fIargs...\\fR]")
+		}
+	} else if len(commands) != 0 {
+		parts = append(parts, "\\fIcommand\\fR")
+	}
+	return strings.Join(parts, " ") + "\n"
+}
+
+func writeFlags(buf *bytes.Buffer, flags []common.Flag) {
+	for _, f := range flags {
+		buf.WriteString(".TP\n.B " + escape(f.String()) + "\n")
+		if description := f.GetDeclaredDescription(); description != "" {
+			writeText(buf, description)
+		}
+		if env := f.GetDeclaredEnv(); env != "" {
+			buf.WriteString("Value is read from environment variable \\fB" + escape(env) + "\\fR if option is not provided.\n")
+		}
+	}
+}
+
+// writeEnvironment writes environment variables of `flags` and variables used by the application itself
+func writeEnvironment(buf *bytes.Buffer, flags []common.Flag) {
+	buf.WriteString(".SH ENVIRONMENT\n")
+	written := make(map[string]bool)
+	for _, f := range flags {
+		env := f.GetDeclaredEnv()
+		if env == "" || written[env] {
+			continue
+		}
+		written[env] = true
+		buf.WriteString(".TP\n.B " + escape(env) + "\n")
+		buf.WriteString("Value of the \\fB" + escape("--"+f.GetName()) + "\\fR option.\n")
+	}
+	buf.WriteString(".TP\n.B " + help.ColumnsEnv + "\n")
+	buf.WriteString("Overrides detected width of the terminal used to format help information.\n")
+}
+
+func writeExamples(buf *bytes.Buffer, appName string, examples []common.Example) {
+	if len(examples) == 0 {
+		return
+	}
+	buf.WriteString(".SH EXAMPLES\n")
+	for _, example := range examples {
+		buf.WriteString(".PP\n")
+		if example.Description != "" {
+			writeText(buf, example.Description)
+		}
+		buf.WriteString(".PP\n.RS\n.nf\n$ " + escape(appName+" "+example.CommandLine) + "\n.fi\n.RE\n")
+	}
+}
+
+func writeText(buf *bytes.Buffer, text string) {
+	for i, paragraph := range strings.Split(text, "\n") {
+		if i != 0 {
+			buf.WriteString(".br\n")
+		}
+		buf.WriteString(escape(paragraph) + "\n")
+	}
+}
+
+// escape escapes characters that have special meaning in roff
+func escape(text string) string {
+	text = strings.Replace(text, "\\", "\\e", -1)
+	text = strings.Replace(text, "-", "\\-", -1)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = "\\&" + text
+	}
+	return text
+}
+
+func quote(text string) string {
+	return "\"" + strings.Replace(escape(text), "\"", "\\(dq", -1) + "\""
+}
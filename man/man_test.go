@@ -0,0 +1,65 @@
+package man
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	stalkflag "github.com/pavelmemory/stalk/flag"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerator_Generate(t *testing.T) {
+	emptyAction := func(ctx common.Runtime) error { return nil }
+	wf := stalk.New().
+		WithName("app").
+		WithDescription("manages cloud resources").
+		WithGlobalFlags(stalkflag.Signal("verbose").WithShortcut('v').WithEnv("APP_VERBOSE").WithDescription("print more details")).
+		WithCommands(
+			command.New("aws").
+				WithDescription("works with aws resources").
+				WithSubCommands(
+					command.New("create").
+						WithDescription("creates new resource\n.dot at line start must be escaped").
+						WithFlags(
+							stalkflag.String("name").WithShortcut('n').Required(true).WithEnv("APP_NAME").WithDescription("name of the resource"),
+							stalkflag.IntWithDefault("count", 1).WithDescription("number of resources")).
+						WithExample("create resource named 'test'", "aws create --name test").
+						WithAction(emptyAction)))
+
+	pages := New(wf).WithDate("January 2018").WithSource("app 1.0").WithManual("App Manual").Generate()
+
+	var names []string
+	for _, page := range pages {
+		names = append(names, page.FileName())
+		golden := filepath.Join("testdata", page.FileName()+".golden")
+		if *update {
+			if err := ioutil.WriteFile(golden, page.Content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(expected) != string(page.Content) {
+			t.Error(page.Name, "\nexpected:\n", string(expected), "\nactual:\n", string(page.Content))
+		}
+	}
+
+	expectedNames := []string{"app.1", "app-aws.1", "app-aws-create.1"}
+	if len(names) != len(expectedNames) {
+		t.Fatal("\nexpected:\n", expectedNames, "\nactual:\n", names)
+	}
+	for i := range names {
+		if names[i] != expectedNames[i] {
+			t.Error("\nexpected:\n", expectedNames, "\nactual:\n", names)
+		}
+	}
+}
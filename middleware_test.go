@@ -0,0 +1,80 @@
+package stalk
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+)
+
+func TestWorkflow_Run_Middlewares(t *testing.T) {
+	var calls []string
+	trace := func(name string) common.Middleware {
+		return func(next common.Action) common.Action {
+			return func(ctx common.Runtime) error {
+				calls = append(calls, name+">"+ctx.CurrentCommand().GetName())
+				err := next(ctx)
+				calls = append(calls, name+"<"+ctx.CurrentCommand().GetName())
+				return err
+			}
+		}
+	}
+	action := func(ctx common.Runtime) error {
+		calls = append(calls, "action "+ctx.CurrentCommand().GetName())
+		return nil
+	}
+
+	err := New().
+		Use(trace("first"), trace("second")).
+		WithCommands(command.New("aws").
+			Use(trace("aws")).
+			WithAction(action).
+			WithSubCommands(command.New("create").
+				Use(trace("create")).
+				WithAction(action))).
+		Run([]string{"aws", "create"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "first>aws, second>aws, aws>aws, action aws, aws<aws, second<aws, first<aws, " +
+		"first>create, second>create, aws>create, create>create, action create, create<create, aws<create, second<create, first<create"
+	if actual := strings.Join(calls, ", "); actual != expected {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+}
+
+func TestWorkflow_Run_MiddlewareShortCircuit(t *testing.T) {
+	denied := errors.New("denied")
+	executed := false
+	err := New().
+		Use(func(next common.Action) common.Action {
+			return func(ctx common.Runtime) error {
+				return denied
+			}
+		}).
+		WithCommands(command.New("cmd").WithAction(func(ctx common.Runtime) error {
+			executed = true
+			return nil
+		})).
+		Run([]string{"cmd"})
+
+	if err != denied {
+		t.Error("middleware error expected, got:", err)
+	}
+	if executed {
+		t.Error("action expected to be skipped")
+	}
+}
+
+func TestWorkflow_Use_Nil(t *testing.T) {
+	err := New().
+		Use(nil).
+		WithCommands(command.New("cmd").Use(nil).WithAction(emptyAction)).
+		Run([]string{"cmd"})
+	if err == nil {
+		t.Fatal("declaration error expected")
+	}
+}
@@ -0,0 +1,79 @@
+package stalk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestWorkflow_Run_PanicRecovery(t *testing.T) {
+	var hooks []string
+	record := func(name string) func(common.Runtime, error) {
+		return func(ctx common.Runtime, err error) {
+			assertErrorCause(t, err, common.ErrorPanic)
+			hooks = append(hooks, name)
+		}
+	}
+
+	err := New().
+		WithCommands(command.New("cmd").
+			WithFlags(flag.Signal("signal")).
+			WithAction(func(ctx common.Runtime) error {
+				// signal flag has no string value, accessor panics on type mismatch
+				ctx.StringFlag("signal")
+				return nil
+			}).
+			WithOnError(record("command OnError")).
+			WithAfter(record("command After"))).
+		WithOnError(record("OnError")).
+		WithCleanup(record("Cleanup")).
+		Run([]string{"cmd", "--signal"})
+
+	assertErrorCause(t, err, common.ErrorPanic)
+	if !strings.Contains(err.Error(), "goroutine") {
+		t.Error("stack trace expected in error:", err)
+	}
+	expected := "command OnError, command After, OnError, Cleanup"
+	if actual := strings.Join(hooks, ", "); actual != expected {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+}
+
+func TestWorkflow_Run_PanicRecovery_Setup(t *testing.T) {
+	cleanedUp := false
+	err := New().
+		WithSetup(func(ctx common.Runtime) error {
+			panic("setup failed")
+		}).
+		WithCleanup(func(ctx common.Runtime, err error) {
+			cleanedUp = true
+		}).
+		WithCommands(command.New("cmd").WithAction(emptyAction)).
+		Run([]string{"cmd"})
+
+	assertErrorCause(t, err, common.ErrorPanic)
+	if !strings.Contains(err.Error(), "setup failed") {
+		t.Error("panic value expected in error:", err)
+	}
+	if !cleanedUp {
+		t.Error("cleanup expected to be executed")
+	}
+}
+
+func TestWorkflow_Run_PanicRecoveryDisabled(t *testing.T) {
+	defer func() {
+		if value := recover(); value != "boom" {
+			t.Error("panic expected to be propagated, got:", value)
+		}
+	}()
+
+	New().
+		WithPanicRecovery(false).
+		WithCommands(command.New("cmd").WithAction(func(ctx common.Runtime) error {
+			panic("boom")
+		})).
+		Run([]string{"cmd"})
+}
@@ -1,29 +1,146 @@
 package stalk
 
 import (
+	stdcontext "context"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pavelmemory/stalk/command"
 	"github.com/pavelmemory/stalk/common"
 	"github.com/pavelmemory/stalk/context"
+	"github.com/pavelmemory/stalk/prompt"
+	"github.com/pavelmemory/stalk/render"
 )
 
-func parse(workflow Workflow, args []string) (common.Runtime, error) {
-	nextStart, parsedGlobalFlags, err := parseFlags(workflow.GetDeclaredGlobalFlags(), args, 0)
-	if err != nil {
-		return nil, err
+// invocation is a result of parsing of provided arguments
+type invocation struct {
+	// runtime is ready to run context, it is 'nil' if help was requested
+	runtime common.Runtime
+	// help is `true` if help flag was found in arguments
+	help bool
+	// path is a chain of found commands
+	path []common.CommandDeclaration
+	// warnings contains messages about usage of deprecated flags and commands
+	warnings []string
+	// prompter asks for values of missing required flags, it is 'nil' if prompting is disabled
+	prompter prompt.Prompter
+	// stdin is a stream values of sensitive flags are read from if '-' used as a value
+	stdin io.Reader
+	// plugin returns command provided by external executable for unknown top-level command, it is 'nil' if plugins are disabled
+	plugin func(name string) (common.CommandDeclaration, bool)
+}
+
+// session contains settings shared by invocations, e.g. by commands executed in the shell
+type session struct {
+	// interactive allows to prompt user for missing values and to read input stream
+	interactive bool
+	// storage is shared by runtimes of invocations, new one is created for each invocation if it is 'nil'
+	storage map[interface{}]interface{}
+	// prompter asks for confirmations if workflow has no declared prompter
+	// It is created once, so input read ahead by it is not lost between invocations
+	prompter prompt.Prompter
+	// runtime of the session receives errors of the lines that didn't create runtime of the invocation
+	runtime common.Runtime
+}
+
+// parse finds flags and commands in `args` and creates runtime to execute them
+func parse(ctx stdcontext.Context, workflow Workflow, args []string, s session) (inv invocation, err error) {
+	if s.interactive {
+		inv.prompter = workflow.GetDeclaredPrompter()
+		inv.stdin, _, _ = workflow.GetDeclaredIO()
+	} else {
+		inv.stdin = strings.NewReader("")
+	}
+	if _, enabled := workflow.GetDeclaredPlugins(); enabled {
+		inv.plugin = func(name string) (common.CommandDeclaration, bool) {
+			return findPlugin(workflow, name)
+		}
+	}
+	helpFlag := workflow.GetDeclaredHelpFlag()
+	globalFlags := workflow.GetDeclaredGlobalFlags()
+	if outputFlag := workflow.GetDeclaredOutputFlag(); outputFlag != nil && !common.IsShadowed(outputFlag, globalFlags) {
+		globalFlags = append(append([]common.Flag(nil), globalFlags...), outputFlag)
+	}
+	nextStart, parsedGlobalFlags, err := parseFlags(globalFlags, helpFlag, args, 0, &inv)
+	if err != nil || inv.help {
+		return
 	}
 
-	argsStart, parsedCommand, err := parseCommands(workflow.GetDeclaredCommands(), args, nextStart)
+	encoder, err := outputEncoder(workflow, parsedGlobalFlags)
 	if err != nil {
-		return nil, err
+		return
+	}
+
+	argsStart, parsedCommand, err := parseCommands(workflow.GetDeclaredCommands(), helpFlag, args, nextStart, &inv)
+	if err != nil || inv.help {
+		return
 	}
 
-	runCtx := context.NewRuntimeContext(parsedGlobalFlags, parsedCommand, args[argsStart:])
-	return runCtx, nil
+	options := context.Options{
+		Timeout:       globalTimeout(workflow, parsedGlobalFlags),
+		RecoverPanics: workflow.GetDeclaredPanicRecovery(),
+		Middlewares:   workflow.GetDeclaredMiddlewares(),
+		Encoder:       encoder,
+	}
+	options.Stdin, options.Stdout, options.Stderr = workflow.GetDeclaredIO()
+	options.Storage = s.storage
+	options.GlobalFlags = globalFlags
+	options.Prompter = inv.prompter
+	if options.Prompter == nil {
+		options.Prompter = s.prompter
+	}
+	if options.Prompter == nil {
+		// non-interactive session never asks, its input is empty
+		options.Prompter = prompt.Terminal(inv.stdin, options.Stderr)
+	}
+	inv.runtime = context.NewRuntimeContext(ctx, options, parsedGlobalFlags, parsedCommand, args[argsStart:])
+	return
+}
+
+// globalTimeout returns value of the timeout flag if it was found or declared workflow timeout otherwise
+func globalTimeout(workflow Workflow, parsedGlobalFlags []common.Flag) time.Duration {
+	timeoutFlag := workflow.GetDeclaredTimeoutFlag()
+	for _, f := range parsedGlobalFlags {
+		if f.GetName() != timeoutFlag {
+			continue
+		}
+		if parsed, ok := f.(common.ParsedDuration); ok {
+			return parsed.DurationValue()
+		}
+	}
+	return workflow.GetDeclaredTimeout()
+}
+
+// outputEncoder returns encoder of the format selected by the output flag or of the default format of the renderer
+func outputEncoder(workflow Workflow, parsedGlobalFlags []common.Flag) (render.Encoder, error) {
+	renderer := workflow.GetDeclaredRenderer()
+	if outputFlag := workflow.GetDeclaredOutputFlag(); outputFlag != nil {
+		for _, f := range parsedGlobalFlags {
+			if f.GetName() != outputFlag.GetName() {
+				continue
+			}
+			if typed, ok := f.(common.Typed); !ok || typed.GetDeclaredTypeName() != "STRING" {
+				break
+			}
+			encoder, err := renderer.Encoder(f.(common.ParsedString).StringValue())
+			if err != nil {
+				return nil, flagValueError(f, err)
+			}
+			return encoder, nil
+		}
+	}
+	encoder, err := renderer.Encoder(renderer.GetDeclaredDefaultFormat())
+	if err != nil {
+		return nil, common.ActionInvalidError("default output format: " + err.Error())
+	}
+	return encoder, nil
 }
 
-func parseCommands(declaredCommands []common.CommandDeclaration, parts []string, start int) (int, common.ParsedCommand, error) {
+func parseCommands(declaredCommands []common.CommandDeclaration, helpFlag common.Flag, parts []string, start int, inv *invocation) (int, common.ParsedCommand, error) {
 	if start >= len(parts) {
 		return start, nil, nil
 	}
@@ -33,40 +150,65 @@ func parseCommands(declaredCommands []common.CommandDeclaration, parts []string,
 		expectedCommandDeclarationsByName[declaredCommand.GetName()] = declaredCommand
 	}
 	if foundCommandDeclaration, found := expectedCommandDeclarationsByName[parts[start]]; found {
+		inv.path = append(inv.path, foundCommandDeclaration)
+		if deprecation := foundCommandDeclaration.GetDeclaredDeprecation(); deprecation != nil {
+			inv.warnings = append(inv.warnings, deprecation.Warning("command '"+commandPath(inv.path)+"'"))
+		}
 		parsedCommand := command.NewParsed(foundCommandDeclaration)
-		nextStart, commandFlags, err := parseFlags(foundCommandDeclaration.GetDeclaredFlags(), parts, start+1)
+		nextStart, commandFlags, err := parseFlags(foundCommandDeclaration.GetDeclaredFlags(), helpFlag, parts, start+1, inv)
 		if err != nil {
 			return 0, nil, err
 		}
+		if inv.help {
+			return nextStart, parsedCommand, nil
+		}
 
 		parsedCommand.Flags(commandFlags)
 
 		if len(foundCommandDeclaration.GetDeclaredSubCommands()) > 0 {
 			var subCmd common.ParsedCommand
-			nextStart, subCmd, err = parseCommands(foundCommandDeclaration.GetDeclaredSubCommands(), parts, nextStart)
-			if err != nil {
+			nextStart, subCmd, err = parseCommands(foundCommandDeclaration.GetDeclaredSubCommands(), helpFlag, parts, nextStart, inv)
+			if err != nil || inv.help {
 				return 0, nil, err
 			}
 			parsedCommand.SubCommand(subCmd)
 		}
 		return nextStart, parsedCommand, nil
 	}
+	if len(inv.path) == 0 && inv.plugin != nil {
+		// the rest of arguments belongs to the plugin, including flags
+		if pluginCommand, found := inv.plugin(parts[start]); found {
+			inv.path = append(inv.path, pluginCommand)
+			return start + 1, command.NewParsed(pluginCommand), nil
+		}
+	}
 	return start, nil, common.NotImplementedError("command: '" + parts[start] + "'")
 }
 
-func parseFlags(expectedFlags []common.Flag, rawInput []string, start int) (lastParsedIndex int, foundFlags []common.Flag, err error) {
+func parseFlags(expectedFlags []common.Flag, helpFlag common.Flag, rawInput []string, start int, inv *invocation) (lastParsedIndex int, foundFlags []common.Flag, err error) {
+	declaredFlagsByName := make(map[string]common.Flag)
 	expectedFlagsByName := make(map[string]common.Flag)
 	expectedFlagsByShortcut := make(map[rune]common.Flag)
 	requiredFlagsByName := make(map[string]common.Flag)
 	for _, flag := range expectedFlags {
+		declaredFlagsByName[flag.GetName()] = flag
 		expectedFlagsByName[flag.GetName()] = flag
-		if flag.GetShortcut() != common.ShortcutNotProvided {
-			expectedFlagsByShortcut[flag.GetShortcut()] = flag
+		if flag.GetDeclaredShortcut() != common.ShortcutNotProvided {
+			expectedFlagsByShortcut[flag.GetDeclaredShortcut()] = flag
 		}
 		if flag.IsDeclaredRequired() {
 			requiredFlagsByName[flag.GetName()] = flag
 		}
 	}
+	helpFlag = addHelpFlag(helpFlag, expectedFlagsByName, expectedFlagsByShortcut)
+	for _, flag := range expectedFlags {
+		if resettable, ok := flag.(common.Resettable); ok {
+			resettable.Reset()
+		}
+	}
+
+	// values of used deprecated flags by flags declared as their replacements
+	var forwarded []forwardedValue
 
 	for lastParsedIndex = start; lastParsedIndex < len(rawInput); lastParsedIndex++ {
 		part := rawInput[lastParsedIndex]
@@ -76,26 +218,104 @@ func parseFlags(expectedFlags []common.Flag, rawInput []string, start int) (last
 
 		var flag common.Flag
 		flag, err = getFlag(part, expectedFlagsByName, expectedFlagsByShortcut)
+		fromFile := false
+		if err != nil {
+			if fileFlag := sensitiveFileFlag(part, expectedFlagsByName); fileFlag != nil {
+				flag, fromFile, err = fileFlag, true, nil
+			}
+		}
 		if err != nil || flag == nil {
 			return
 		}
 
+		if flag == helpFlag {
+			inv.help = true
+			return lastParsedIndex + 1, nil, nil
+		}
+
 		if flag.IsDeclaredRequired() {
 			delete(requiredFlagsByName, flag.GetName())
 		}
 
 		delete(expectedFlagsByName, flag.GetName())
-		delete(expectedFlagsByShortcut, flag.GetShortcut())
+		delete(expectedFlagsByShortcut, flag.GetDeclaredShortcut())
+		value := ""
 		if !flag.IsDeclaredSignal() {
 			if lastParsedIndex+1 >= len(rawInput) {
 				return 0, nil, common.NotAllRequiredValuesError(flag.String())
 			}
 			lastParsedIndex++
-			if err := flag.Parse(rawInput[lastParsedIndex]); err != nil {
+			value, err = sensitiveValue(flag, rawInput[lastParsedIndex], fromFile, inv.stdin)
+			if err != nil {
 				return 0, nil, err
 			}
+			if err := flag.Parse(value); err != nil {
+				return 0, nil, flagValueError(flag, err)
+			}
 		}
 		foundFlags = append(foundFlags, flag)
+
+		if deprecation := flag.GetDeclaredDeprecation(); deprecation != nil {
+			inv.warnings = append(inv.warnings, deprecation.Warning("flag '--"+flag.GetName()+"'"))
+			if replacement, found := declaredFlagsByName[deprecation.Replacement]; found && replacement != flag && replacement.IsDeclaredSignal() == flag.IsDeclaredSignal() {
+				forwarded = append(forwarded, forwardedValue{flag: replacement, value: value})
+			}
+		}
+	}
+
+	// replacement flags provided explicitly take precedence over values of deprecated flags
+	for _, fv := range forwarded {
+		if _, expected := expectedFlagsByName[fv.flag.GetName()]; !expected {
+			continue
+		}
+		if !fv.flag.IsDeclaredSignal() {
+			if err := fv.flag.Parse(fv.value); err != nil {
+				return 0, nil, flagValueError(fv.flag, err)
+			}
+		}
+		delete(requiredFlagsByName, fv.flag.GetName())
+		delete(expectedFlagsByName, fv.flag.GetName())
+		foundFlags = append(foundFlags, fv.flag)
+	}
+
+	// flags not provided in command line take values from environment variables
+	for _, flag := range expectedFlags {
+		if _, expected := expectedFlagsByName[flag.GetName()]; !expected || flag.GetDeclaredEnv() == "" {
+			continue
+		}
+		value, found := os.LookupEnv(flag.GetDeclaredEnv())
+		if !found || value == "" {
+			continue
+		}
+		if flag.IsDeclaredSignal() {
+			set, err := strconv.ParseBool(value)
+			if err != nil {
+				return 0, nil, flagValueError(flag, err)
+			}
+			if !set {
+				continue
+			}
+		} else if err := flag.Parse(value); err != nil {
+			return 0, nil, flagValueError(flag, err)
+		}
+		delete(requiredFlagsByName, flag.GetName())
+		delete(expectedFlagsByName, flag.GetName())
+		foundFlags = append(foundFlags, flag)
+	}
+
+	if len(requiredFlagsByName) != 0 && inv.prompter != nil && inv.prompter.IsInteractive() {
+		// asks in order of declaration
+		for _, flag := range expectedFlags {
+			if _, missing := requiredFlagsByName[flag.GetName()]; !missing {
+				continue
+			}
+			if err := inv.prompter.Prompt(flag); err != nil {
+				return 0, nil, flagValueError(flag, err)
+			}
+			delete(requiredFlagsByName, flag.GetName())
+			delete(expectedFlagsByName, flag.GetName())
+			foundFlags = append(foundFlags, flag)
+		}
 	}
 
 	if len(requiredFlagsByName) != 0 {
@@ -114,6 +334,41 @@ func parseFlags(expectedFlags []common.Flag, rawInput []string, start int) (last
 	return
 }
 
+// forwardedValue is a value of deprecated flag that should be used as a value of its replacement
+type forwardedValue struct {
+	flag  common.Flag
+	value string
+}
+
+func commandPath(path []common.CommandDeclaration) string {
+	var names []string
+	for _, cmd := range path {
+		names = append(names, cmd.GetName())
+	}
+	return strings.Join(names, " ")
+}
+
+// addHelpFlag registers help flag as expected if it is not shadowed by declared flag with the same name or shortcut
+// Returns help flag if it was registered or 'nil' otherwise
+func addHelpFlag(helpFlag common.Flag, expectedFlagsByName map[string]common.Flag, expectedFlagsByShortcut map[rune]common.Flag) common.Flag {
+	if helpFlag == nil {
+		return nil
+	}
+	if _, found := expectedFlagsByName[helpFlag.GetName()]; found {
+		return nil
+	}
+	shortcut := helpFlag.GetDeclaredShortcut()
+	if _, found := expectedFlagsByShortcut[shortcut]; found && shortcut != common.ShortcutNotProvided {
+		return nil
+	}
+
+	expectedFlagsByName[helpFlag.GetName()] = helpFlag
+	if shortcut != common.ShortcutNotProvided {
+		expectedFlagsByShortcut[shortcut] = helpFlag
+	}
+	return helpFlag
+}
+
 func getFlag(part string, expectedFlagsByName map[string]common.Flag, expectedFlagsByShortcut map[rune]common.Flag) (common.Flag, error) {
 	switch {
 	case strings.HasPrefix(part, "--"):
@@ -138,3 +393,46 @@ func getFlag(part string, expectedFlagsByName map[string]common.Flag, expectedFl
 		return nil, nil
 	}
 }
+
+// sensitiveFileFlag returns sensitive flag if `part` is its name with `common.FileFlagSuffix`, e.g. '--password-file'
+func sensitiveFileFlag(part string, expectedFlagsByName map[string]common.Flag) common.Flag {
+	if !strings.HasPrefix(part, "--") || !strings.HasSuffix(part, common.FileFlagSuffix) {
+		return nil
+	}
+	f, found := expectedFlagsByName[strings.TrimSuffix(part[2:], common.FileFlagSuffix)]
+	if !found || f.IsDeclaredSignal() || !f.IsDeclaredSensitive() {
+		return nil
+	}
+	return f
+}
+
+// sensitiveValue returns value of the sensitive flag read from the file or from `stdin`
+// Values of other flags are returned as is
+func sensitiveValue(flag common.Flag, value string, fromFile bool, stdin io.Reader) (string, error) {
+	var content []byte
+	var err error
+	switch {
+	case fromFile:
+		content, err = ioutil.ReadFile(value)
+	case flag.IsDeclaredSensitive() && value == common.StdinValue:
+		content, err = ioutil.ReadAll(stdin)
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return "", common.FlagValueInvalidError("--" + flag.GetName() + ": " + err.Error())
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// flagValueError reports that value of the `flag` can't be parsed
+// Details of the error are omitted for sensitive flags as they may contain the value
+func flagValueError(flag common.Flag, err error) error {
+	if _, ok := err.(common.Error); ok {
+		return err
+	}
+	if flag.IsDeclaredSensitive() {
+		return common.FlagValueInvalidError("--" + flag.GetName() + ": " + common.MaskedValue)
+	}
+	return common.FlagValueInvalidError("--" + flag.GetName() + ": " + err.Error())
+}
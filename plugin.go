@@ -0,0 +1,144 @@
+package stalk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+)
+
+// findPlugin returns command executing plugin of the `workflow` for command `name` if its executable is found
+func findPlugin(workflow Workflow, name string) (common.CommandDeclaration, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, "-") {
+		return nil, false
+	}
+	file := workflow.GetDeclaredName() + "-" + name
+	dirs, _ := workflow.GetDeclaredPlugins()
+	for _, dir := range dirs {
+		if path, err := exec.LookPath(filepath.Join(dir, file)); err == nil {
+			return pluginCommand(workflow, name, path), true
+		}
+	}
+	if path, err := exec.LookPath(file); err == nil {
+		return pluginCommand(workflow, name, path), true
+	}
+	return nil, false
+}
+
+// discoverPlugins returns commands executing plugins of the `workflow` found in plugin directories and 'PATH'
+// Plugins shadowed by declared commands or by plugins found earlier are skipped
+func discoverPlugins(workflow Workflow) []common.CommandDeclaration {
+	dirs, enabled := workflow.GetDeclaredPlugins()
+	if !enabled {
+		return nil
+	}
+
+	found := make(map[string]bool)
+	for _, cmd := range workflow.GetDeclaredCommands() {
+		found[cmd.GetName()] = true
+	}
+	prefix := workflow.GetDeclaredName() + "-"
+	var plugins []common.CommandDeclaration
+	for _, dir := range append(append([]string(nil), dirs...), filepath.SplitList(os.Getenv("PATH"))...) {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := strings.TrimPrefix(entry.Name(), prefix)
+			if runtime.GOOS == "windows" {
+				// executables are found by names without extension
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if !strings.HasPrefix(entry.Name(), prefix) || name == "" || found[name] {
+				continue
+			}
+			path, err := exec.LookPath(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			found[name] = true
+			plugins = append(plugins, pluginCommand(workflow, name, path))
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].GetName() < plugins[j].GetName() })
+	return plugins
+}
+
+// pluginCommand creates command that executes plugin located by `path` with arguments of the command
+func pluginCommand(workflow Workflow, name, path string) common.CommandDeclaration {
+	return command.New(name).
+		WithDescription("plugin " + path).
+		WithAction(func(ctx common.Runtime) error {
+			plugin := exec.CommandContext(ctx.Context(), path, ctx.GetArgs()...)
+			plugin.Stdin, plugin.Stdout, plugin.Stderr = ctx.Stdin(), ctx.Stdout(), ctx.Stderr()
+			plugin.Env = append(os.Environ(), pluginEnv(workflow, ctx)...)
+			err := plugin.Run()
+			if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+				return common.PluginExitError{Plugin: filepath.Base(path), Code: exitErr.ExitCode()}
+			}
+			return err
+		})
+}
+
+// pluginEnv returns environment variables with values of the global flags found in `ctx`
+func pluginEnv(workflow Workflow, ctx common.Runtime) []string {
+	var env []string
+	for _, f := range workflow.GetDeclaredGlobalFlags() {
+		name := f.GetName()
+		if !ctx.HasGlobalFlag(name) {
+			continue
+		}
+		value := "true"
+		if !f.IsDeclaredSignal() {
+			value = globalFlagValue(f, ctx)
+		}
+		variable := f.GetDeclaredEnv()
+		if variable == "" {
+			variable = envName(workflow.GetDeclaredName() + "_" + name)
+		}
+		env = append(env, variable+"="+value)
+	}
+	return env
+}
+
+// globalFlagValue returns value of the global flag `f` found in `ctx` as a string
+func globalFlagValue(f common.Flag, ctx common.Runtime) string {
+	typeName := ""
+	if typed, ok := f.(common.Typed); ok {
+		typeName = typed.GetDeclaredTypeName()
+	}
+	name := f.GetName()
+	switch typeName {
+	case "STRING":
+		return ctx.StringGlobalFlag(name)
+	case "INT":
+		return fmt.Sprint(ctx.IntGlobalFlag(name))
+	case "FLOAT":
+		return fmt.Sprint(ctx.FloatGlobalFlag(name))
+	case "BOOL":
+		return fmt.Sprint(ctx.BoolGlobalFlag(name))
+	case "DURATION":
+		return ctx.DurationGlobalFlag(name).String()
+	default:
+		return fmt.Sprint(ctx.CustomGlobalFlag(name))
+	}
+}
+
+// envName converts `name` into name of the environment variable, e.g. 'app_dry-run' into 'APP_DRY_RUN'
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,133 @@
+package stalk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+// writePlugins creates directory with executable shell scripts named by keys of `scripts`
+func writePlugins(t *testing.T, scripts map[string]string) string {
+	dir := t.TempDir()
+	for name, script := range scripts {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestWorkflow_Run_Plugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := writePlugins(t, map[string]string{
+		"app-deploy": `echo "deploy $* verbose=$APP_VERBOSE region=$REGION retries=$APP_MAX_RETRIES"`,
+		"app-fail":   "echo failed >&2\nexit 3",
+		"app-print":  "echo plugin print",
+		"other-echo": "echo other",
+		"app-data":   "",
+	})
+	if err := os.Chmod(filepath.Join(dir, "app-data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	pathDir := writePlugins(t, map[string]string{"app-status": "echo status from PATH"})
+	t.Setenv("PATH", pathDir)
+	t.Setenv("REGION", "")
+
+	for index, scenario := range []struct {
+		enabled  bool
+		args     []string
+		out      string
+		errOut   string
+		err      string
+		exitCode int
+	}{
+		/*1*/ {true, []string{"-v", "--region", "eu", "deploy", "--force", "-h", "web"}, "deploy --force -h web verbose=true region=eu retries=\n", "", "", ExitOK},
+		/*2*/ {true, []string{"--max-retries", "2", "deploy"}, "deploy  verbose= region= retries=2\n", "", "", ExitOK},
+		/*3*/ {true, []string{"status"}, "status from PATH\n", "", "", ExitOK},
+		/*4*/ {true, []string{"fail"}, "", "failed\n", "plugin 'app-fail' exited with status 3", 3},
+		/*5*/ {true, []string{"print", "x"}, "declared print\n", "", "", ExitOK},
+		/*6*/ {true, []string{"echo"}, "", "", "it is not implemented yet: command: 'echo'", ExitUsage},
+		/*7*/ {true, []string{"data"}, "", "", "it is not implemented yet: command: 'data'", ExitUsage},
+		/*8*/ {false, []string{"deploy"}, "", "", "it is not implemented yet: command: 'deploy'", ExitUsage},
+	} {
+		out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+		w := New().
+			WithName("app").
+			WithIO(strings.NewReader(""), out, errOut).
+			WithPlugins(scenario.enabled, dir).
+			WithGlobalFlags(
+				flag.Signal("verbose").WithShortcut('v'),
+				flag.String("region").WithEnv("REGION"),
+				flag.Int("max-retries")).
+			WithCommands(command.New("print").WithAction(func(ctx common.Runtime) error {
+				_, err := ctx.Stdout().Write([]byte("declared print\n"))
+				return err
+			}))
+
+		err := w.Run(scenario.args)
+		actualErr := ""
+		if err != nil {
+			actualErr = err.Error()
+		}
+		if scenario.err != actualErr {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.err, "\nactual:\n", actualErr)
+		}
+		if actual := ExitCode(err); scenario.exitCode != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.exitCode, "\nactual:\n", actual)
+		}
+		if actual := out.String(); scenario.out != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.out, "\nactual:\n", actual)
+		}
+		if actual := errOut.String(); scenario.errOut != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.errOut, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestWorkflow_Run_PluginsHelp(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := writePlugins(t, map[string]string{"app-deploy": "", "app-print": ""})
+	t.Setenv("PATH", writePlugins(t, map[string]string{"app-deploy": "", "app-status": ""}))
+	t.Setenv("COLUMNS", "200")
+
+	out := &bytes.Buffer{}
+	w := New().
+		WithName("app").
+		WithIO(strings.NewReader(""), out, &bytes.Buffer{}).
+		WithPlugins(true, dir).
+		WithCommands(command.New("print").WithDescription("prints arguments").WithAction(func(ctx common.Runtime) error { return nil }))
+
+	if err := w.Run([]string{"--help"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `Usage: app [global flags] <command>
+
+Commands:
+  print                           prints arguments
+
+Plugins:
+  deploy                          plugin ` + filepath.Join(dir, "app-deploy") + `
+  status                          plugin ` + filepath.Join(os.Getenv("PATH"), "app-status") + `
+
+Global flags:
+  [--output|-o]? <STRING, table>  output format: table, json, yaml, csv or template=TEXT
+  [--script]? [STRING]            execute command lines from the file, '-' reads them from the input
+  [--help|-h]?                    show help information
+`
+	if actual := out.String(); expected != actual {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+}
@@ -0,0 +1,168 @@
+// Package prompt asks user for values of required flags missing in command line
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/internal/term"
+)
+
+// MaxAttempts is a number of times user is asked for the value of the flag before error is returned
+const MaxAttempts = 3
+
+// Prompter asks user for values of flags
+type Prompter interface {
+	// IsInteractive returns `true` if user can answer questions
+	// If it returns `false` missing required flags are reported as an error
+	IsInteractive() bool
+	// Prompt asks user for the value of `flag` and parses it with `Flag.Parse`
+	Prompt(flag common.Flag) error
+	// Confirm asks user to confirm action described by `message`, returns `true` if user agreed
+	Confirm(message string) (bool, error)
+}
+
+// Question returns text used to ask user for the value of `flag`, e.g. 'name of the resource (--name STRING): '
+func Question(flag common.Flag) string {
+	subject := "--" + flag.GetName()
+	if typed, ok := flag.(common.Typed); ok && typed.GetDeclaredTypeName() != "" {
+		subject += " " + typed.GetDeclaredTypeName()
+	}
+	if description := flag.GetDeclaredDescription(); description != "" {
+		return description + " (" + subject + "): "
+	}
+	return subject + ": "
+}
+
+// IsSensitive returns `true` if `flag` implements `common.Sensitive` and its value is a secret
+func IsSensitive(flag common.Flag) bool {
+	sensitive, ok := flag.(common.Sensitive)
+	return ok && sensitive.IsDeclaredSensitive()
+}
+
+// Terminal creates prompter that reads answers from `in` and writes questions into `out`
+// It is interactive only if `in` is a terminal, input of sensitive flags is not echoed
+func Terminal(in io.Reader, out io.Writer) Prompter {
+	return &terminal{in: in, reader: bufio.NewReader(in), out: out}
+}
+
+var _ Prompter = (*terminal)(nil)
+
+type terminal struct {
+	in     io.Reader
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+func (t *terminal) IsInteractive() bool {
+	return term.IsTerminalStream(t.in)
+}
+
+func (t *terminal) Prompt(flag common.Flag) error {
+	for attempt := 1; ; attempt++ {
+		fmt.Fprint(t.out, Question(flag))
+		value, err := t.readLine(IsSensitive(flag))
+		if err != nil {
+			return err
+		}
+
+		if value == "" {
+			err = errors.New("value is required")
+		} else {
+			err = flag.Parse(value)
+		}
+		if err == nil || attempt == MaxAttempts {
+			return err
+		}
+		if IsSensitive(flag) {
+			fmt.Fprintln(t.out, "invalid value")
+		} else {
+			fmt.Fprintln(t.out, "invalid value: "+err.Error())
+		}
+	}
+}
+
+func (t *terminal) Confirm(message string) (bool, error) {
+	fmt.Fprint(t.out, message+" [y/N]: ")
+	answer, err := t.readLine(false)
+	if err != nil {
+		return false, err
+	}
+	return IsYes(answer), nil
+}
+
+// IsYes returns `true` if `answer` is a positive answer: 'y' or 'yes' in any case
+func IsYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// readLine reads answer without trailing line break, echo is turned off for sensitive values
+func (t *terminal) readLine(sensitive bool) (string, error) {
+	if fd, ok := term.FileDescriptor(t.in); ok && sensitive && term.IsTerminal(fd) {
+		restore, err := term.DisableEcho(fd)
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			restore()
+			// line break typed by user was not echoed
+			fmt.Fprintln(t.out)
+		}()
+	}
+
+	line, err := t.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Script is a prompter that answers questions with predefined values, it is useful for tests
+type Script struct {
+	answers []string
+	asked   []string
+}
+
+var _ Prompter = (*Script)(nil)
+
+// Scripted creates prompter that answers questions with `answers` in provided order
+func Scripted(answers ...string) *Script {
+	return &Script{answers: answers}
+}
+
+// IsInteractive always returns `true`
+func (s *Script) IsInteractive() bool {
+	return true
+}
+
+// Prompt parses next answer as a value of `flag`
+func (s *Script) Prompt(flag common.Flag) error {
+	s.asked = append(s.asked, flag.GetName())
+	if len(s.answers) == 0 {
+		return errors.New("no answer for flag '--" + flag.GetName() + "'")
+	}
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	return flag.Parse(answer)
+}
+
+// Confirm uses next answer as a reply to confirmation, see `IsYes`
+func (s *Script) Confirm(message string) (bool, error) {
+	s.asked = append(s.asked, message)
+	if len(s.answers) == 0 {
+		return false, errors.New("no answer for confirmation '" + message + "'")
+	}
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	return IsYes(answer), nil
+}
+
+// Asked returns names of flags and confirmation messages prompted so far
+func (s *Script) Asked() []string {
+	return s.asked
+}
@@ -0,0 +1,77 @@
+package prompt
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestQuestion(t *testing.T) {
+	for index, scenario := range []struct {
+		question string
+		expected string
+	}{
+		/*1*/ {Question(flag.String("name").WithDescription("name of the resource")), "name of the resource (--name STRING): "},
+		/*2*/ {Question(flag.Int("count")), "--count INT: "},
+	} {
+		if scenario.expected != scenario.question {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", scenario.question)
+		}
+	}
+}
+
+func TestTerminal_Prompt(t *testing.T) {
+	out := &bytes.Buffer{}
+	prompter := Terminal(strings.NewReader("\nmany\n42\nlast"), out)
+	if prompter.IsInteractive() {
+		t.Error("reader is not a terminal")
+	}
+
+	count := flag.Int("count")
+	if err := prompter.Prompt(count); err != nil {
+		t.Fatal(err)
+	}
+	if actual := count.(interface{ IntValue() int64 }).IntValue(); actual != 42 {
+		t.Error("parsed value expected to be 42, got:", actual)
+	}
+	expected := "--count INT: invalid value: value is required\n" +
+		"--count INT: invalid value: strconv.ParseInt: parsing \"many\": invalid syntax\n" +
+		"--count INT: "
+	if actual := out.String(); expected != actual {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+
+	name := flag.String("name")
+	if err := prompter.Prompt(name); err != nil {
+		t.Fatal(err)
+	}
+	if actual := name.(interface{ StringValue() string }).StringValue(); actual != "last" {
+		t.Error("answer without line break expected to be accepted, got:", actual)
+	}
+	if err := prompter.Prompt(name); err == nil {
+		t.Error("error expected on end of input")
+	}
+}
+
+func TestTerminal_Prompt_MaxAttempts(t *testing.T) {
+	prompter := Terminal(strings.NewReader("a\nb\nc\n1\n"), &bytes.Buffer{})
+	if err := prompter.Prompt(flag.Int("count")); err == nil {
+		t.Error("error expected after all attempts")
+	}
+}
+
+func TestScripted(t *testing.T) {
+	script := Scripted("one")
+	if err := script.Prompt(flag.String("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := script.Prompt(flag.String("second")); err == nil {
+		t.Error("error expected when answers are exhausted")
+	}
+	if expected, actual := []string{"first", "second"}, script.Asked(); !reflect.DeepEqual(expected, actual) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+}
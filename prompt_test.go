@@ -0,0 +1,85 @@
+package stalk
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+	"github.com/pavelmemory/stalk/prompt"
+)
+
+func TestWorkflow_Run_Prompting(t *testing.T) {
+	var name string
+	var size int64
+	create := command.New("create").
+		WithFlags(
+			flag.String("name").Required(true),
+			flag.Int("size").Required(true),
+			flag.String("zone").Required(true)).
+		WithAction(func(ctx common.Runtime) error {
+			name, size = ctx.StringFlag("name"), ctx.IntFlag("size")
+			return nil
+		})
+
+	script := prompt.Scripted("db", "20")
+	err := New().
+		WithPrompter(script).
+		WithCommands(create).
+		Run([]string{"create", "--zone", "eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "db" || size != 20 {
+		t.Error("prompted values expected, got:", name, size)
+	}
+	if expected, actual := []string{"name", "size"}, script.Asked(); !reflect.DeepEqual(expected, actual) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+
+	err = New().
+		WithPrompter(prompt.Scripted("db", "twenty")).
+		WithCommands(create).
+		Run([]string{"create", "--zone", "eu"})
+	assertErrorCause(t, err, common.ErrorFlagValueInvalid)
+}
+
+func TestWorkflow_Run_PromptingNotInteractive(t *testing.T) {
+	err := New().
+		WithPrompter(prompt.Terminal(&bytes.Buffer{}, &bytes.Buffer{})).
+		WithCommands(command.New("create").
+			WithFlags(flag.String("name").Required(true)).
+			WithAction(emptyAction)).
+		Run([]string{"create"})
+	assertErrorCause(t, err, common.ErrorNotAllRequiredFlags)
+}
+
+func TestWorkflow_Run_DefaultPrompterReused(t *testing.T) {
+	w := New().
+		WithIO(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{}).
+		WithCommands(command.New("delete").WithConfirmation("sure?").WithAction(emptyAction))
+	// default prompter stands for the terminal, it must be the same for all invocations
+	script := prompt.Scripted("y", "y", "y")
+	w.(*workflow).terminal = script
+
+	for i := 0; i < 2; i++ {
+		if err := w.Run([]string{"delete"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := w.RunScript(strings.NewReader("delete\n"))
+	assertErrorCause(t, errors.Unwrap(err), common.ErrorAborted)
+
+	if expected, actual := []string{"sure?", "sure?"}, script.Asked(); !reflect.DeepEqual(expected, actual) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+
+	w.WithIO(strings.NewReader(""), nil, nil)
+	if w.(*workflow).terminal == script {
+		t.Error("default prompter expected to be replaced with the input stream")
+	}
+}
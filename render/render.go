@@ -0,0 +1,315 @@
+// Package render encodes values returned by commands in the output format selected by the user
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/pavelmemory/stalk/internal/yaml"
+)
+
+// Names of formats supported by default
+const (
+	FormatTable    = "table"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatCSV      = "csv"
+	FormatTemplate = "template"
+)
+
+// TagName is a name of the struct field tag that defines column name used by table and CSV formats
+// Fields tagged with '-' are skipped
+const TagName = "render"
+
+// Encoder writes value into output stream in some format
+type Encoder interface {
+	// Encode writes `value` into `out`
+	Encode(out io.Writer, value interface{}) error
+}
+
+// EncoderFunc allows to use ordinary function as an Encoder
+type EncoderFunc func(out io.Writer, value interface{}) error
+
+// Encode calls `f(out, value)`
+func (f EncoderFunc) Encode(out io.Writer, value interface{}) error {
+	return f(out, value)
+}
+
+// Factory creates encoder configured with the argument of the format
+// Format is selected as 'name' or 'name=argument', e.g. 'template={{.Name}}'
+type Factory func(argument string) (Encoder, error)
+
+// Renderer holds supported output formats
+type Renderer interface {
+	// WithFormat registers format with `name`, registered format with the same name is replaced
+	WithFormat(name string, factory Factory) Renderer
+	// GetDeclaredFormats returns sorted names of registered formats
+	GetDeclaredFormats() []string
+	// WithDefaultFormat sets format used if it was not selected by the user
+	WithDefaultFormat(format string) Renderer
+	// GetDeclaredDefaultFormat returns format used if it was not selected by the user, `FormatTable` by default
+	GetDeclaredDefaultFormat() string
+	// Encoder returns encoder for the `format` in 'name' or 'name=argument' form
+	Encoder(format string) (Encoder, error)
+}
+
+// New creates renderer that supports table, JSON, YAML, CSV and template formats
+func New() Renderer {
+	return &renderer{
+		defaultFormat: FormatTable,
+		factories: map[string]Factory{
+			FormatTable: withoutArgument(FormatTable, Table()),
+			FormatJSON:  withoutArgument(FormatJSON, JSON()),
+			FormatYAML:  withoutArgument(FormatYAML, YAML()),
+			FormatCSV:   withoutArgument(FormatCSV, CSV()),
+			FormatTemplate: func(argument string) (Encoder, error) {
+				return Template(argument)
+			},
+		},
+	}
+}
+
+var _ Renderer = (*renderer)(nil)
+
+type renderer struct {
+	defaultFormat string
+	factories     map[string]Factory
+}
+
+func (r *renderer) WithFormat(name string, factory Factory) Renderer {
+	r.factories[name] = factory
+	return r
+}
+
+func (r *renderer) GetDeclaredFormats() []string {
+	var names []string
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *renderer) WithDefaultFormat(format string) Renderer {
+	r.defaultFormat = format
+	return r
+}
+
+func (r *renderer) GetDeclaredDefaultFormat() string {
+	return r.defaultFormat
+}
+
+func (r *renderer) Encoder(format string) (Encoder, error) {
+	name, argument := format, ""
+	if i := strings.Index(format, "="); i >= 0 {
+		name, argument = format[:i], format[i+1:]
+	}
+	factory, found := r.factories[name]
+	if !found || factory == nil {
+		return nil, errors.New("unsupported output format '" + name + "', supported: " + strings.Join(r.GetDeclaredFormats(), ", "))
+	}
+	return factory(argument)
+}
+
+func withoutArgument(name string, encoder Encoder) Factory {
+	return func(argument string) (Encoder, error) {
+		if argument != "" {
+			return nil, errors.New("output format '" + name + "' doesn't accept argument")
+		}
+		return encoder, nil
+	}
+}
+
+// JSON returns encoder that writes value as indented JSON
+func JSON() Encoder {
+	return EncoderFunc(func(out io.Writer, value interface{}) error {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	})
+}
+
+// YAML returns encoder that writes value as YAML document
+// Struct fields are named by `yaml` or `json` tags
+func YAML() Encoder {
+	return EncoderFunc(func(out io.Writer, value interface{}) error {
+		document, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(document)
+		return err
+	})
+}
+
+// CSV returns encoder that writes value as comma-separated values
+// Header is written for structs and maps, see `Table` for supported values
+func CSV() Encoder {
+	return EncoderFunc(func(out io.Writer, value interface{}) error {
+		header, rows := tabulate(value)
+		w := csv.NewWriter(out)
+		if header != nil {
+			if err := w.Write(header); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteAll(rows); err != nil {
+			return err
+		}
+		return w.Error()
+	})
+}
+
+// Table returns encoder that writes value as text table with aligned columns
+// Columns of structs and slices of structs are built from exported fields,
+// maps are written as key and value columns, other values as a single column
+// Column name is a value of `render` tag or name of the field
+func Table() Encoder {
+	return EncoderFunc(func(out io.Writer, value interface{}) error {
+		header, rows := tabulate(value)
+		w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		if header != nil {
+			upper := make([]string, len(header))
+			for i, name := range header {
+				upper[i] = strings.ToUpper(name)
+			}
+			fmt.Fprintln(w, strings.Join(upper, "\t"))
+		}
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	})
+}
+
+// Template returns encoder that executes Go template `text` with the value
+func Template(text string) (Encoder, error) {
+	if text == "" {
+		return nil, errors.New("output format '" + FormatTemplate + "' requires template, e.g. 'template={{.Name}}'")
+	}
+	tmpl, err := template.New(FormatTemplate).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return EncoderFunc(func(out io.Writer, value interface{}) error {
+		return tmpl.Execute(out, value)
+	}), nil
+}
+
+// tabulate converts value into rows of cells with optional header
+func tabulate(value interface{}) ([]string, [][]string) {
+	v := indirect(reflect.ValueOf(value))
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := columns(v.Type())
+		return header(fields), [][]string{row(v, fields)}
+	case reflect.Map:
+		keys := v.MapKeys()
+		rows := make([][]string, 0, len(keys))
+		for _, key := range keys {
+			rows = append(rows, []string{cell(key), cell(v.MapIndex(key))})
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			return rows[i][0] < rows[j][0]
+		})
+		return []string{"key", "value"}, rows
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		elemType := v.Type().Elem()
+		for elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		var rows [][]string
+		if elemType.Kind() != reflect.Struct {
+			for i := 0; i < v.Len(); i++ {
+				rows = append(rows, []string{cell(v.Index(i))})
+			}
+			return nil, rows
+		}
+		fields := columns(elemType)
+		for i := 0; i < v.Len(); i++ {
+			elem := indirect(v.Index(i))
+			if !elem.IsValid() {
+				rows = append(rows, make([]string, len(fields)))
+				continue
+			}
+			rows = append(rows, row(elem, fields))
+		}
+		return header(fields), rows
+	}
+	return nil, [][]string{{cell(v)}}
+}
+
+type column struct {
+	name  string
+	index int
+}
+
+// columns returns exported fields of the struct type not skipped by the tag
+func columns(t reflect.Type) []column {
+	var fields []column
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get(TagName)
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, column{name: name, index: i})
+	}
+	return fields
+}
+
+func header(fields []column) []string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.name
+	}
+	return names
+}
+
+func row(v reflect.Value, fields []column) []string {
+	cells := make([]string, len(fields))
+	for i, field := range fields {
+		cells[i] = cell(v.Field(field.index))
+	}
+	return cells
+}
+
+func cell(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// indirect dereferences pointers and interfaces, returns invalid value for 'nil'
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
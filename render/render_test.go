@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+type resource struct {
+	Name    string
+	Region  string `render:"zone" json:"region"`
+	Size    int    `json:"size,omitempty"`
+	Secret  string `render:"-" json:"-"`
+	private string
+}
+
+func TestEncoders(t *testing.T) {
+	resources := []*resource{
+		{Name: "db", Region: "eu-west-1", Size: 20},
+		{Name: "cache, fast", Region: "us-east-1", Secret: "hidden"},
+	}
+	tmpl, err := Template("{{range .}}{{.Name}};{{end}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for index, scenario := range []struct {
+		encoder  Encoder
+		value    interface{}
+		expected string
+	}{
+		/*1*/ {Table(), resources, `NAME          ZONE        SIZE
+db            eu-west-1   20
+cache, fast   us-east-1   0
+`},
+		/*2*/ {Table(), map[string]int{"b": 2, "a": 1}, `KEY   VALUE
+a     1
+b     2
+`},
+		/*3*/ {Table(), []string{"one", "two"}, "one\ntwo\n"},
+		/*4*/ {Table(), "plain", "plain\n"},
+		/*5*/ {CSV(), resources, `Name,zone,Size
+db,eu-west-1,20
+"cache, fast",us-east-1,0
+`},
+		/*6*/ {JSON(), resources[0], `{
+  "Name": "db",
+  "region": "eu-west-1",
+  "size": 20
+}
+`},
+		/*7*/ {YAML(), resources, `- Name: db
+  region: eu-west-1
+  size: 20
+- Name: cache, fast
+  region: us-east-1
+`},
+		/*8*/ {tmpl, resources, "db;cache, fast;"},
+	} {
+		buf := &bytes.Buffer{}
+		if err := scenario.encoder.Encode(buf, scenario.value); err != nil {
+			t.Fatal(index+1, err)
+		}
+		if actual := buf.String(); scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestRenderer_Encoder(t *testing.T) {
+	renderer := New().WithFormat("name", func(argument string) (Encoder, error) {
+		return EncoderFunc(func(out io.Writer, value interface{}) error {
+			_, err := io.WriteString(out, argument+":"+value.(*resource).Name)
+			return err
+		}), nil
+	})
+
+	for index, scenario := range []struct {
+		format   string
+		expected string
+		fails    bool
+	}{
+		/*1*/ {"name=prefix", "prefix:db", false},
+		/*2*/ {"template={{.Name}} in {{.Region}}", "db in eu", false},
+		/*3*/ {"json=compact", "", true},
+		/*4*/ {"template", "", true},
+		/*5*/ {"template={{.Name", "", true},
+		/*6*/ {"xml", "", true},
+	} {
+		encoder, err := renderer.Encoder(scenario.format)
+		if scenario.fails {
+			if err == nil {
+				t.Error("index:", index+1, "error expected")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(index+1, err)
+		}
+		buf := &bytes.Buffer{}
+		if err := encoder.Encode(buf, &resource{Name: "db", Region: "eu"}); err != nil {
+			t.Fatal(index+1, err)
+		}
+		if actual := buf.String(); scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+
+	expected := "csv, json, name, table, template, yaml"
+	if actual := strings.Join(renderer.GetDeclaredFormats(), ", "); expected != actual {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+}
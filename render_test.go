@@ -0,0 +1,56 @@
+package stalk
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestRuntime_Render(t *testing.T) {
+	type bucket struct {
+		Name string `json:"name"`
+		Size int    `json:"size"`
+	}
+	list := command.New("list").WithAction(func(ctx common.Runtime) error {
+		return ctx.Render([]bucket{{Name: "logs", Size: 10}})
+	})
+
+	for index, scenario := range []struct {
+		globalFlags []common.Flag
+		args        []string
+		expected    string
+	}{
+		/*1*/ {nil, []string{"list"}, "NAME   SIZE\nlogs   10\n"},
+		/*2*/ {nil, []string{"-o", "json", "list"}, "[\n  {\n    \"name\": \"logs\",\n    \"size\": 10\n  }\n]\n"},
+		/*3*/ {nil, []string{"--output", "yaml", "list"}, "- name: logs\n  size: 10\n"},
+		/*4*/ {nil, []string{"--output", "template={{range .}}{{.Name}}{{end}}", "list"}, "logs"},
+		/*5*/ {[]common.Flag{flag.StringWithDefault("output", "csv")}, []string{"list"}, "Name,Size\nlogs,10\n"},
+		/*6*/ {[]common.Flag{flag.String("out").WithShortcut('o')}, []string{"-o", "json", "list"}, "NAME   SIZE\nlogs   10\n"},
+	} {
+		out := &bytes.Buffer{}
+		err := New().
+			WithIO(nil, out, nil).
+			WithGlobalFlags(scenario.globalFlags...).
+			WithCommands(list).
+			Run(scenario.args)
+		if err != nil {
+			t.Fatal(index+1, err)
+		}
+		if actual := out.String(); scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestRuntime_Render_UnsupportedFormat(t *testing.T) {
+	err := New().
+		WithCommands(command.New("list").WithAction(emptyAction)).
+		Run([]string{"--output", "xml", "list"})
+	assertErrorCause(t, err, common.ErrorFlagValueInvalid)
+	if ExitCode(err) != ExitUsage {
+		t.Error("usage exit code expected for unsupported format")
+	}
+}
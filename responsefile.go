@@ -0,0 +1,90 @@
+package stalk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pavelmemory/stalk/common"
+)
+
+// responseFilePrefix marks argument that is a path of the response file
+const responseFilePrefix = "@"
+
+// expandResponseFiles replaces arguments '@path' with arguments read from the response files
+// Nested response files are resolved relative to the directory of the file that refers to them
+func expandResponseFiles(args []string) ([]string, error) {
+	return expandArgs(args, "", nil)
+}
+
+// expandArgs expands response files found in `args`, relative paths are resolved against `dir`
+// `chain` holds absolute paths of the files being expanded and is used to detect cycles
+func expandArgs(args []string, dir string, chain []string) ([]string, error) {
+	var expanded []string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, responseFilePrefix+responseFilePrefix):
+			expanded = append(expanded, arg[1:])
+		case strings.HasPrefix(arg, responseFilePrefix) && len(arg) > 1:
+			fileArgs, err := readResponseFile(arg[1:], dir, chain)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, fileArgs...)
+		default:
+			expanded = append(expanded, arg)
+		}
+	}
+	return expanded, nil
+}
+
+// readResponseFile reads arguments from the file located by `path` and expands response files nested in it
+// Error of reading the file is reported as a syntax error of the argument that refers to it
+func readResponseFile(path, dir string, chain []string) ([]string, error) {
+	arg := responseFilePrefix + path
+	if !filepath.IsAbs(path) && dir != "" {
+		path = filepath.Join(dir, path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, visited := range chain {
+		if visited == absPath {
+			cycle := append(append([]string(nil), chain[i:]...), absPath)
+			return nil, common.CommandLineSyntaxError("cycle of response files: " + strings.Join(cycle, " -> "))
+		}
+	}
+	chain = append(chain, absPath)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, common.CommandLineSyntaxError("argument '" + arg + "': " + err.Error())
+	}
+	defer file.Close()
+
+	var args []string
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), scriptComment) {
+			continue
+		}
+		lineArgs, err := splitCommandLine(line)
+		if err == nil {
+			lineArgs, err = expandArgs(lineArgs, filepath.Dir(path), chain)
+		}
+		if err != nil {
+			if _, nested := err.(common.LineError); nested {
+				return nil, err
+			}
+			return nil, common.LineError{Source: path, Line: number, Err: err}
+		}
+		args = append(args, lineArgs...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, common.CommandLineSyntaxError("argument '" + arg + "': " + err.Error())
+	}
+	return args, nil
+}
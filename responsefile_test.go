@@ -0,0 +1,95 @@
+package stalk
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/common"
+)
+
+func writeResponseFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "stalk-response")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExpandResponseFiles(t *testing.T) {
+	dir := writeResponseFiles(t, map[string]string{
+		"args.txt":        "# common arguments\n--name 'web server'\n  # indented comment\n@nested/more.txt \"a b\"\n",
+		"nested/more.txt": "--region eu\n@@literal\n",
+		"empty.txt":       "",
+		"cycle/a.txt":     "one\n@b.txt\n",
+		"cycle/b.txt":     "two\n\n@a.txt\n",
+		"broken.txt":      "ok\n'unterminated\n",
+		"missing.txt":     "@nothing.txt\n",
+	})
+	defer os.RemoveAll(dir)
+
+	for index, scenario := range []struct {
+		args     []string
+		expected []string
+		err      string
+	}{
+		/*1*/ {[]string{"print", "@" + filepath.Join(dir, "args.txt"), "last"}, []string{"print", "--name", "web server", "--region", "eu", "@literal", "a b", "last"}, ""},
+		/*2*/ {[]string{"print", "@" + filepath.Join(dir, "empty.txt")}, []string{"print"}, ""},
+		/*3*/ {[]string{"@", "@@x", "a@b"}, []string{"@", "@x", "a@b"}, ""},
+		/*4*/ {[]string{"@" + filepath.Join(dir, "cycle", "a.txt")}, nil, filepath.Join(dir, "cycle", "b.txt") + ":3: wrong command line syntax: cycle of response files: " +
+			filepath.Join(dir, "cycle", "a.txt") + " -> " + filepath.Join(dir, "cycle", "b.txt") + " -> " + filepath.Join(dir, "cycle", "a.txt")},
+		/*5*/ {[]string{"@" + filepath.Join(dir, "broken.txt")}, nil, filepath.Join(dir, "broken.txt") + ":2: wrong command line syntax: unterminated single quote: 'unterminated"},
+		/*6*/ {[]string{"@" + filepath.Join(dir, "missing.txt")}, nil, filepath.Join(dir, "missing.txt") + ":1: wrong command line syntax: argument '@nothing.txt': open " + filepath.Join(dir, "nothing.txt") + ": no such file or directory"},
+		/*7*/ {[]string{"print", "@" + filepath.Join(dir, "absent.txt")}, nil, "wrong command line syntax: argument '@" + filepath.Join(dir, "absent.txt") + "': open " + filepath.Join(dir, "absent.txt") + ": no such file or directory"},
+	} {
+		actual, err := expandResponseFiles(scenario.args)
+		actualErr := ""
+		if err != nil {
+			actualErr = err.Error()
+		}
+		if scenario.err != actualErr {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.err, "\nactual:\n", actualErr)
+		}
+		if !reflect.DeepEqual(scenario.expected, actual) {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+		if err != nil && ExitCode(err) != ExitUsage {
+			t.Error("index:", index+1, "usage exit code expected, got:", ExitCode(err))
+		}
+	}
+}
+
+func TestWorkflow_Run_ResponseFiles(t *testing.T) {
+	dir := writeResponseFiles(t, map[string]string{"args.txt": "print\none 'two three'\n"})
+	defer os.RemoveAll(dir)
+	args := []string{"@" + filepath.Join(dir, "args.txt")}
+
+	var events []string
+	out := &bytes.Buffer{}
+	if err := scriptWorkflow(&events, out).WithResponseFiles(true).Run(args); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "one\ntwo three\n"; out.String() != expected {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", out.String())
+	}
+
+	err := scriptWorkflow(&events, out).Run(args)
+	var stalkErr common.Error
+	if !errors.As(err, &stalkErr) || stalkErr.Cause != common.ErrorNotImplemented || !strings.Contains(err.Error(), "@") {
+		t.Error("response files must be disabled by default:", err)
+	}
+}
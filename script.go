@@ -0,0 +1,215 @@
+package stalk
+
+import (
+	"bufio"
+	stdcontext "context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pavelmemory/stalk/common"
+)
+
+// ScriptErrorPolicy defines what happens with the rest of the script after the line that ended with an error
+type ScriptErrorPolicy byte
+
+const (
+	// StopOnError stops execution of the script on the first failed line
+	StopOnError ScriptErrorPolicy = iota
+	// ContinueOnError executes all lines of the script regardless of errors
+	ContinueOnError
+)
+
+// scriptComment starts the line that is not executed
+const scriptComment = "#"
+
+func (w *workflow) RunScript(script io.Reader) error {
+	return w.RunScriptContext(stdcontext.Background(), script)
+}
+
+func (w *workflow) RunScriptContext(ctx stdcontext.Context, script io.Reader) error {
+	// execution impossible because of invalid declarations
+	if len(w.declErrs) != 0 {
+		return common.DeclarationErrors(w.declErrs)
+	}
+
+	source := "script"
+	if named, ok := script.(interface{ Name() string }); ok {
+		source = named.Name()
+	}
+
+	return w.runSession(ctx, func(s session) error {
+		var lineErrs common.LineErrors
+		lines := newScriptReader(script)
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			number, line, err := lines.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			args, err := scriptArgs(line, s.storage)
+			if err == nil && len(args) == 0 {
+				continue
+			}
+			if err == nil && args[0] == shellExit {
+				break
+			}
+			if err == nil {
+				err = w.executeLine(ctx, args, s)
+			} else {
+				err = w.handleError(s.runtime, err)
+			}
+			if err == nil {
+				continue
+			}
+
+			lineErr := common.LineError{Source: source, Line: number, Err: err}
+			if w.GetDeclaredScriptErrorPolicy() != ContinueOnError {
+				return lineErr
+			}
+			lineErrs = append(lineErrs, lineErr)
+		}
+
+		if len(lineErrs) != 0 {
+			return lineErrs
+		}
+		return nil
+	})
+}
+
+// scriptPath returns path of the script if `cmd` consists of the script flag with value only
+func (w *workflow) scriptPath(cmd []string) (string, bool, error) {
+	scriptFlag := w.GetDeclaredScriptFlag()
+	if scriptFlag == nil || common.IsShadowed(scriptFlag, w.GetDeclaredGlobalFlags()) {
+		return "", false, nil
+	}
+
+	name := "--" + scriptFlag.GetName()
+	var path string
+	var rest []string
+	switch {
+	case strings.HasPrefix(cmd[0], name+"="):
+		path, rest = cmd[0][len(name)+1:], cmd[1:]
+	case cmd[0] == name || scriptFlag.GetDeclaredShortcut() != common.ShortcutNotProvided && cmd[0] == "-"+string(scriptFlag.GetDeclaredShortcut()):
+		if len(cmd) < 2 {
+			return "", true, common.NotAllRequiredValuesError("flag '" + name + "' requires path of the script")
+		}
+		path, rest = cmd[1], cmd[2:]
+	default:
+		return "", false, nil
+	}
+
+	if len(rest) != 0 {
+		return "", true, common.CommandLineSyntaxError("flag '" + name + "' can't be combined with other arguments: " + strings.Join(rest, " "))
+	}
+	return path, true, nil
+}
+
+// runScriptFile executes the script from the file located by `path` or from the input stream if `path` is '-'
+func (w *workflow) runScriptFile(ctx stdcontext.Context, path string) error {
+	if path == common.StdinValue {
+		in, _, _ := w.GetDeclaredIO()
+		return w.RunScriptContext(ctx, in)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return w.RunScriptContext(ctx, file)
+}
+
+// scriptReader reads logical lines of the script joining continued lines
+type scriptReader struct {
+	scanner *bufio.Scanner
+	number  int
+}
+
+func newScriptReader(script io.Reader) *scriptReader {
+	return &scriptReader{scanner: bufio.NewScanner(script)}
+}
+
+// next returns number of the first physical line and content of the logical line
+// It returns `io.EOF` when the script is over
+func (r *scriptReader) next() (int, string, error) {
+	var line strings.Builder
+	start := 0
+	for r.scanner.Scan() {
+		r.number++
+		if start == 0 {
+			start = r.number
+		}
+		text := strings.TrimRight(r.scanner.Text(), "\r")
+		if !strings.HasSuffix(text, `\`) {
+			line.WriteString(text)
+			return start, line.String(), nil
+		}
+		line.WriteString(strings.TrimSuffix(text, `\`))
+	}
+	if err := r.scanner.Err(); err != nil {
+		return 0, "", err
+	}
+	if start != 0 {
+		// continuation on the last line of the script
+		return start, line.String(), nil
+	}
+	return 0, "", io.EOF
+}
+
+// scriptArgs splits `line` of the script into arguments and substitutes variables in each of them
+// Comment lines have no arguments
+func scriptArgs(line string, storage map[interface{}]interface{}) ([]string, error) {
+	if strings.HasPrefix(strings.TrimSpace(line), scriptComment) {
+		return nil, nil
+	}
+	words, err := splitWords(line)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	for _, w := range words {
+		arg, err := substitute(w, storage)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// substitute replaces '${name}' in the argument `w` with the value stored by key 'name'
+// The value becomes a part of the same argument regardless of its content
+// Variables in single quotes or with escaped characters, e.g. '\${name}', are left as is
+func substitute(w word, storage map[interface{}]interface{}) (string, error) {
+	var result strings.Builder
+	for i := 0; i < len(w.runes); i++ {
+		if w.literal[i] || w.runes[i] != '$' || i+1 >= len(w.runes) || w.literal[i+1] || w.runes[i+1] != '{' {
+			result.WriteRune(w.runes[i])
+			continue
+		}
+		end := i + 2
+		for end < len(w.runes) && w.runes[end] != '}' {
+			end++
+		}
+		if end == len(w.runes) {
+			return "", common.CommandLineSyntaxError("unterminated variable: " + string(w.runes[i:]))
+		}
+		name := string(w.runes[i+2 : end])
+		value, found := storage[name]
+		if !found {
+			return "", common.CommandLineSyntaxError("undefined variable: " + name)
+		}
+		result.WriteString(fmt.Sprint(value))
+		i = end
+	}
+	return result.String(), nil
+}
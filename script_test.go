@@ -0,0 +1,229 @@
+package stalk
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+// scriptWorkflow creates workflow with commands that store and print values, 'fail' command always ends with an error
+func scriptWorkflow(events *[]string, out *bytes.Buffer) Workflow {
+	return New().
+		WithName("app").
+		WithIO(strings.NewReader(""), out, &bytes.Buffer{}).
+		WithSetup(func(ctx common.Runtime) error {
+			*events = append(*events, "setup")
+			ctx.Set("region", "eu-west")
+			return nil
+		}).
+		WithCleanup(func(ctx common.Runtime, err error) {
+			*events = append(*events, "cleanup")
+		}).
+		WithCommands(
+			command.New("create").
+				WithFlags(flag.String("name").Required(true), flag.String("region").Required(true)).
+				WithAction(func(ctx common.Runtime) error {
+					id := ctx.StringFlag("name") + "@" + ctx.StringFlag("region")
+					*events = append(*events, "create "+id)
+					ctx.Set("id", id)
+					return nil
+				}),
+			command.New("print").
+				WithAction(func(ctx common.Runtime) error {
+					*events = append(*events, "print "+strings.Join(ctx.GetArgs(), ","))
+					return ctx.Render(ctx.GetArgs())
+				}),
+			command.New("fail").
+				WithAction(func(ctx common.Runtime) error {
+					*events = append(*events, "fail")
+					return errors.New("failed")
+				}),
+		)
+}
+
+func TestWorkflow_RunScript(t *testing.T) {
+	var events []string
+	out := &bytes.Buffer{}
+	err := scriptWorkflow(&events, out).RunScript(strings.NewReader(`# creates resource
+create --name web \
+  --region ${region}
+
+print ${id} "${region} zone" \${id}
+  # indented comment
+exit
+fail
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"setup", "create web@eu-west", "print web@eu-west,eu-west zone,${id}", "cleanup"}
+	if !reflect.DeepEqual(expected, events) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", events)
+	}
+	if expected := "web@eu-west\neu-west zone\n${id}\n"; out.String() != expected {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", out.String())
+	}
+}
+
+func TestWorkflow_RunScript_SubstitutionIsSingleArgument(t *testing.T) {
+	var events []string
+	err := scriptWorkflow(&events, &bytes.Buffer{}).RunScript(strings.NewReader(`create --name 'web server --force' --region eu
+print ${id} '${id}'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"setup", "create web server --force@eu", "print web server --force@eu,${id}", "cleanup"}
+	if !reflect.DeepEqual(expected, events) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", events)
+	}
+}
+
+func TestScriptArgs(t *testing.T) {
+	storage := map[interface{}]interface{}{"v": "a b --name 'x'", "n": 1}
+	for index, scenario := range []struct {
+		line     string
+		expected []string
+		err      string
+	}{
+		/*1*/ {`print ${v}`, []string{"print", "a b --name 'x'"}, ""},
+		/*2*/ {`print '${v}'`, []string{"print", "${v}"}, ""},
+		/*3*/ {`print "${v} ${n}"`, []string{"print", "a b --name 'x' 1"}, ""},
+		/*4*/ {`print \${v} pre${n}post`, []string{"print", "${v}", "pre1post"}, ""},
+		/*5*/ {`print '$'{v} $`, []string{"print", "${v}", "$"}, ""},
+		/*6*/ {`  # ${missing}`, nil, ""},
+		/*7*/ {`print ${missing}`, nil, "wrong command line syntax: undefined variable: missing"},
+		/*8*/ {`print ${v`, nil, "wrong command line syntax: unterminated variable: ${v"},
+	} {
+		actual, err := scriptArgs(scenario.line, storage)
+		actualErr := ""
+		if err != nil {
+			actualErr = err.Error()
+		}
+		if scenario.err != actualErr {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.err, "\nactual:\n", actualErr)
+		}
+		if !reflect.DeepEqual(scenario.expected, actual) {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestWorkflow_RunScript_Errors(t *testing.T) {
+	script := "print one\nfail\n\nprint ${missing}\ncreate --name db\nprint two\n"
+	for index, scenario := range []struct {
+		policy   ScriptErrorPolicy
+		events   []string
+		expected string
+	}{
+		/*1*/ {
+			StopOnError,
+			[]string{"setup", "print one", "fail", "cleanup"},
+			"script:2: failed",
+		},
+		/*2*/ {
+			ContinueOnError,
+			[]string{"setup", "print one", "fail", "print two", "cleanup"},
+			"script:2: failed\n" +
+				"script:4: wrong command line syntax: undefined variable: missing\n" +
+				"script:5: not all required flags provided: [--region] [STRING]",
+		},
+	} {
+		var events []string
+		err := scriptWorkflow(&events, &bytes.Buffer{}).
+			WithScriptErrorPolicy(scenario.policy).
+			RunScript(strings.NewReader(script))
+		if err == nil || err.Error() != scenario.expected {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", err)
+		}
+		if !reflect.DeepEqual(scenario.events, events) {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.events, "\nactual:\n", events)
+		}
+	}
+
+	var lineErr common.LineError
+	err := scriptWorkflow(new([]string), &bytes.Buffer{}).RunScript(strings.NewReader("print\n\nprint 'unterminated\n"))
+	if !errors.As(err, &lineErr) || lineErr.Line != 3 || ExitCode(err) != ExitUsage {
+		t.Error("unexpected error:", err)
+	}
+}
+
+func TestWorkflow_RunScript_OnError(t *testing.T) {
+	script := "fail\nmissing\nprint ${missing}\nprint one\n"
+	for index, scenario := range []struct {
+		policy   ScriptErrorPolicy
+		expected []string
+	}{
+		/*1*/ {StopOnError, []string{"failed"}},
+		/*2*/ {ContinueOnError, []string{
+			"failed",
+			"it is not implemented yet: command: 'missing'",
+			"wrong command line syntax: undefined variable: missing",
+		}},
+	} {
+		var errs []string
+		err := scriptWorkflow(new([]string), &bytes.Buffer{}).
+			WithScriptErrorPolicy(scenario.policy).
+			WithOnError(func(ctx common.Runtime, err error) {
+				errs = append(errs, err.Error())
+			}).
+			RunScript(strings.NewReader(script))
+		if err == nil {
+			t.Error("index:", index+1, "error expected")
+		}
+		if !reflect.DeepEqual(scenario.expected, errs) {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", errs)
+		}
+	}
+}
+
+func TestWorkflow_Run_ScriptFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stalk-script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "deploy.txt")
+	if err := ioutil.WriteFile(path, []byte("print from file\nfail\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for index, scenario := range []struct {
+		args     []string
+		stdin    string
+		events   []string
+		expected string
+	}{
+		/*1*/ {[]string{"--script", path}, "", []string{"setup", "print from,file", "fail", "cleanup"}, path + ":2: failed"},
+		/*2*/ {[]string{"--script=" + path}, "", []string{"setup", "print from,file", "fail", "cleanup"}, path + ":2: failed"},
+		/*3*/ {[]string{"--script", "-"}, "print stdin", []string{"setup", "print stdin", "cleanup"}, ""},
+		/*4*/ {[]string{"--script", path, "print"}, "", nil, "wrong command line syntax: flag '--script' can't be combined with other arguments: print"},
+		/*5*/ {[]string{"--script"}, "", nil, "not all required values provided: flag '--script' requires path of the script"},
+	} {
+		var events []string
+		err := scriptWorkflow(&events, &bytes.Buffer{}).
+			WithIO(strings.NewReader(scenario.stdin), nil, nil).
+			Run(scenario.args)
+		actual := ""
+		if err != nil {
+			actual = err.Error()
+		}
+		if scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+		if !reflect.DeepEqual(scenario.events, events) {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.events, "\nactual:\n", events)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package stalk
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestWorkflow_Run_Sensitive(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := ioutil.WriteFile(passwordFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for index, scenario := range []struct {
+		args     []string
+		stdin    string
+		expected string
+	}{
+		/*1*/ {[]string{"login", "--password", "inline"}, "", "inline"},
+		/*2*/ {[]string{"login", "--password-file", passwordFile}, "", "from-file"},
+		/*3*/ {[]string{"login", "-p", "-"}, "from-stdin\n", "from-stdin"},
+	} {
+		var actual string
+		err := New().
+			WithIO(strings.NewReader(scenario.stdin), nil, nil).
+			WithCommands(command.New("login").
+				WithFlags(flag.String("password").WithShortcut('p').Sensitive().Required(true)).
+				WithAction(func(ctx common.Runtime) error {
+					actual = ctx.StringFlag("password")
+					return nil
+				})).
+			Run(scenario.args)
+		if err != nil {
+			t.Fatal(index+1, err)
+		}
+		if scenario.expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestWorkflow_Run_SensitiveErrors(t *testing.T) {
+	workflow := New().
+		WithCommands(command.New("login").
+			WithFlags(
+				flag.Int("pin").Sensitive(),
+				flag.String("user")).
+			WithAction(emptyAction))
+
+	err := workflow.Run([]string{"login", "--pin", "12x4"})
+	assertErrorCause(t, err, common.ErrorFlagValueInvalid)
+	if strings.Contains(err.Error(), "12x4") {
+		t.Error("sensitive value revealed in error:", err)
+	}
+
+	err = workflow.Run([]string{"login", "--user-file", "users.txt"})
+	assertErrorCause(t, err, common.ErrorFlagNotSupported)
+
+	err = workflow.Run([]string{"login", "--pin-file", filepath.Join(t.TempDir(), "missing")})
+	assertErrorCause(t, err, common.ErrorFlagValueInvalid)
+}
@@ -0,0 +1,269 @@
+package stalk
+
+import (
+	stdcontext "context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/context"
+	"github.com/pavelmemory/stalk/help"
+	"github.com/pavelmemory/stalk/internal/readline"
+)
+
+// Built-in commands of the shell
+const (
+	shellExit = "exit"
+	shellHelp = "help"
+)
+
+func (w *workflow) Shell() error {
+	return w.ShellContext(stdcontext.Background())
+}
+
+func (w *workflow) ShellContext(ctx stdcontext.Context) error {
+	// execution impossible because of invalid declarations
+	if len(w.declErrs) != 0 {
+		return common.DeclarationErrors(w.declErrs)
+	}
+
+	in, out, errOut := w.GetDeclaredIO()
+	return w.runSession(ctx, func(s session) error {
+		s.interactive, s.prompter = true, w.terminal
+		editor := readline.New(in, out, w.complete)
+		prompt := w.GetDeclaredName() + "> "
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			line, err := editor.ReadLine(prompt)
+			switch {
+			case err == io.EOF:
+				return nil
+			case err == readline.ErrInterrupted:
+				continue
+			case err != nil:
+				return err
+			}
+
+			args, err := splitCommandLine(line)
+			if err != nil {
+				fmt.Fprintln(errOut, "error: "+w.handleError(s.runtime, err).Error())
+				continue
+			}
+			if len(args) == 0 {
+				continue
+			}
+			if args[0] == shellExit {
+				return nil
+			}
+			if err := w.executeLine(ctx, args, s); err != nil {
+				fmt.Fprintln(errOut, "error: "+err.Error())
+			}
+		}
+	})
+}
+
+// runSession executes `Setup` once, then `body` and `Cleanup` once at the end
+// Values stored in runtime by `Setup` and by commands executed in `body` are shared through storage of the session
+// `OnError` is not executed for `common.LineError` and `common.LineErrors` returned by `body`,
+// errors of the lines are handled by `executeLine` or `handleError` when they happen
+func (w *workflow) runSession(ctx stdcontext.Context, body func(s session) error) (err error) {
+	in, out, errOut := w.GetDeclaredIO()
+	storage := make(map[interface{}]interface{})
+	options := context.Options{
+		RecoverPanics: w.GetDeclaredPanicRecovery(),
+		Stdin:         in,
+		Stdout:        out,
+		Stderr:        errOut,
+		Storage:       storage,
+	}
+	sessionCtx := context.NewRuntimeContext(ctx, options, nil, nil, nil)
+
+	defer func() {
+		if err != nil && !isLineError(err) {
+			if onError := w.GetDeclaredOnError(); onError != nil {
+				if panicErr := w.callHook(onError, sessionCtx, err); panicErr != nil {
+					err = panicErr
+				}
+			}
+		}
+		if cleanup := w.GetDeclaredCleanup(); cleanup != nil {
+			if panicErr := w.callHook(cleanup, sessionCtx, err); panicErr != nil {
+				err = panicErr
+			}
+		}
+	}()
+
+	if setup := w.GetDeclaredSetup(); setup != nil {
+		if err = w.call(setup, sessionCtx); err != nil {
+			return
+		}
+	}
+	return body(session{storage: storage, runtime: sessionCtx})
+}
+
+// isLineError returns `true` if `err` consists of errors of the lines
+func isLineError(err error) bool {
+	switch err.(type) {
+	case common.LineError, common.LineErrors:
+		return true
+	}
+	return false
+}
+
+// executeLine runs commands found in `args` of the shell or script line, `OnError` is executed if they fail
+// Runtime of the session is passed to `OnError` if the line can't be parsed
+func (w *workflow) executeLine(ctx stdcontext.Context, args []string, s session) (err error) {
+	runCtx := s.runtime
+	defer func() {
+		if err != nil {
+			err = w.handleError(runCtx, err)
+		}
+	}()
+
+	if args[0] == shellHelp {
+		path, err := findCommandPath(w.GetDeclaredCommands(), args[1:])
+		if err != nil {
+			return err
+		}
+		return w.printHelp(path)
+	}
+
+	inv, err := parse(ctx, w, args, s)
+	if err != nil {
+		return err
+	}
+	if sink := w.GetDeclaredWarningSink(); sink != nil {
+		for _, warning := range inv.warnings {
+			sink(warning)
+		}
+	}
+	if inv.help {
+		return w.printHelp(inv.path)
+	}
+
+	runCtx = inv.runtime
+	return w.call(func(ctx common.Runtime) error {
+		return ctx.Run()
+	}, runCtx)
+}
+
+// handleError executes `OnError` with `err` of the line
+// It returns `err` or error of `OnError` if it panicked
+func (w *workflow) handleError(runCtx common.Runtime, err error) error {
+	if onError := w.GetDeclaredOnError(); onError != nil {
+		if panicErr := w.callHook(onError, runCtx, err); panicErr != nil {
+			return panicErr
+		}
+	}
+	return err
+}
+
+// findCommandPath returns chain of declared commands named by `names`
+func findCommandPath(commands []common.CommandDeclaration, names []string) ([]common.CommandDeclaration, error) {
+	var path []common.CommandDeclaration
+	for _, name := range names {
+		cmd := findCommand(commands, name)
+		if cmd == nil {
+			return nil, common.NotImplementedError("command: '" + name + "'")
+		}
+		path = append(path, cmd)
+		commands = cmd.GetDeclaredSubCommands()
+	}
+	return path, nil
+}
+
+func findCommand(commands []common.CommandDeclaration, name string) common.CommandDeclaration {
+	for _, cmd := range commands {
+		if cmd.GetName() == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func findFlag(flags []common.Flag, part string) common.Flag {
+	for _, f := range flags {
+		if f == nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(part, "--"):
+			if f.GetName() == part[2:] {
+				return f
+			}
+		case len(part) > 1 && f.GetDeclaredShortcut() == rune(part[1]):
+			return f
+		}
+	}
+	return nil
+}
+
+// complete returns candidates for the word `partial` of the shell line that follows `head`
+// Candidates are names of commands and flags available at that position
+func (w *workflow) complete(head, partial string) []string {
+	args, err := splitCommandLine(head)
+	if err != nil {
+		return nil
+	}
+
+	var builtins []string
+	if len(args) == 0 {
+		builtins = []string{shellExit, shellHelp}
+	}
+	helpOnly := len(args) != 0 && args[0] == shellHelp
+	if helpOnly {
+		args = args[1:]
+	}
+
+	flags := append(append([]common.Flag(nil), w.GetDeclaredGlobalFlags()...), w.GetDeclaredOutputFlag(), w.GetDeclaredHelpFlag())
+	visibleFlags := help.GlobalFlags(w.GetDeclaredGlobalFlags(), w.GetDeclaredOutputFlag(), w.GetDeclaredHelpFlag())
+	commands := w.GetDeclaredCommands()
+	for i := 0; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "-") {
+			if f := findFlag(flags, args[i]); f != nil && !f.IsDeclaredSignal() {
+				if i+1 == len(args) {
+					// partial is a value of the flag
+					return nil
+				}
+				i++
+			}
+			continue
+		}
+		cmd := findCommand(commands, args[i])
+		if cmd == nil {
+			return nil
+		}
+		flags = append(append([]common.Flag(nil), cmd.GetDeclaredFlags()...), w.GetDeclaredHelpFlag())
+		visibleFlags = help.GlobalFlags(cmd.GetDeclaredFlags(), w.GetDeclaredHelpFlag())
+		commands = cmd.GetDeclaredSubCommands()
+	}
+
+	var candidates []string
+	if strings.HasPrefix(partial, "-") {
+		if helpOnly {
+			return nil
+		}
+		for _, f := range visibleFlags {
+			candidates = append(candidates, "--"+f.GetName())
+		}
+	} else {
+		candidates = builtins
+		for _, cmd := range common.VisibleCommands(commands) {
+			candidates = append(candidates, cmd.GetName())
+		}
+	}
+
+	var matched []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, partial) {
+			matched = append(matched, candidate)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
@@ -0,0 +1,122 @@
+package stalk
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestWorkflow_Shell(t *testing.T) {
+	var events []string
+	input := strings.NewReader(`count --by 2
+count
+
+'unterminated
+missing
+help count
+count --by 5
+exit
+count
+`)
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	err := New().
+		WithName("app").
+		WithIO(input, out, errOut).
+		WithSetup(func(ctx common.Runtime) error {
+			events = append(events, "setup")
+			ctx.Set("total", int64(0))
+			return nil
+		}).
+		WithCleanup(func(ctx common.Runtime, err error) {
+			events = append(events, "cleanup")
+		}).
+		WithOnError(func(ctx common.Runtime, err error) {
+			events = append(events, "error")
+		}).
+		WithCommands(command.New("count").
+			WithDescription("adds value to the total").
+			WithFlags(flag.IntWithDefault("by", 1)).
+			WithAction(func(ctx common.Runtime) error {
+				total, _ := ctx.Get("total")
+				ctx.Set("total", total.(int64)+ctx.IntFlag("by"))
+				total, _ = ctx.Get("total")
+				events = append(events, "count")
+				return ctx.Render(total)
+			})).
+		Shell()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := []string{"setup", "count", "count", "error", "error", "count", "cleanup"}, events; !reflect.DeepEqual(expected, actual) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+	if !strings.HasPrefix(out.String(), "2\n3\nUsage: app [global flags] count [flags] [args...]") || !strings.HasSuffix(out.String(), "8\n") {
+		t.Error("unexpected output:\n", out.String())
+	}
+	expectedErrors := "error: wrong command line syntax: unterminated single quote: 'unterminated\n" +
+		"error: it is not implemented yet: command: 'missing'\n"
+	if actual := errOut.String(); expectedErrors != actual {
+		t.Error("\nexpected:\n", expectedErrors, "\nactual:\n", actual)
+	}
+}
+
+func TestWorkflow_Shell_OnError(t *testing.T) {
+	var events []string
+	err := New().
+		WithIO(strings.NewReader("fail\nfail\n"), &bytes.Buffer{}, &bytes.Buffer{}).
+		WithOnError(func(ctx common.Runtime, err error) {
+			events = append(events, "error")
+		}).
+		WithCleanup(func(ctx common.Runtime, err error) {
+			events = append(events, "cleanup")
+		}).
+		WithCommands(command.New("fail").WithAction(func(ctx common.Runtime) error {
+			panic("fail")
+		})).
+		Shell()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := []string{"error", "error", "cleanup"}, events; !reflect.DeepEqual(expected, actual) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+}
+
+func TestWorkflow_Complete(t *testing.T) {
+	w := New().
+		WithGlobalFlags(flag.String("region").WithShortcut('r'), flag.Signal("debug").Hidden()).
+		WithCommands(
+			command.New("aws").WithSubCommands(
+				command.New("create").WithFlags(flag.String("name"), flag.Signal("dry-run")),
+				command.New("copy"),
+				command.New("internal").Hidden()),
+			command.New("azure")).(*workflow)
+
+	for index, scenario := range []struct {
+		head     string
+		partial  string
+		expected []string
+	}{
+		/*1*/ {"", "", []string{"aws", "azure", "exit", "help"}},
+		/*2*/ {"", "a", []string{"aws", "azure"}},
+		/*3*/ {"", "--", []string{"--help", "--output", "--region"}},
+		/*4*/ {"-r eu ", "aws", []string{"aws"}},
+		/*5*/ {"--region ", "", nil},
+		/*6*/ {"aws ", "c", []string{"copy", "create"}},
+		/*7*/ {"aws create --dry-run ", "--n", []string{"--name"}},
+		/*8*/ {"aws create ", "", nil},
+		/*9*/ {"help aws ", "cr", []string{"create"}},
+		/*10*/ {"unknown ", "", nil},
+	} {
+		actual := w.complete(scenario.head, scenario.partial)
+		if !reflect.DeepEqual(scenario.expected, actual) {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package stalk
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type signalHandling struct {
+	gracePeriod time.Duration
+	source      <-chan os.Signal
+	// exit terminates the process on the second signal
+	exit func(code int)
+}
+
+// trap starts listening for interruption signals
+// On the first signal it is passed into returned channel and `cancel` is called,
+// on the second signal the process exits
+// Returned function stops listening and must be called once execution is over
+func (sh *signalHandling) trap(cancel func()) (<-chan os.Signal, func()) {
+	source := sh.source
+	stopNotify := func() {}
+	if source == nil {
+		notified := make(chan os.Signal, 2)
+		signal.Notify(notified, os.Interrupt, syscall.SIGTERM)
+		source = notified
+		stopNotify = func() {
+			signal.Stop(notified)
+		}
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-source:
+			interrupts <- sig
+			cancel()
+		case <-done:
+			return
+		}
+
+		select {
+		case <-source:
+			sh.exit(ExitInterrupted)
+		case <-done:
+		}
+	}()
+
+	return interrupts, func() {
+		close(done)
+		stopNotify()
+	}
+}
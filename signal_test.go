@@ -0,0 +1,119 @@
+package stalk
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestWorkflow_Run_SignalHandling(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	var cleanupErr error
+	err := New().
+		WithSignalHandling(time.Second, signals).
+		WithCleanup(func(ctx common.Runtime, err error) {
+			cleanupErr = err
+		}).
+		WithCommands(command.New("wait").WithAction(func(ctx common.Runtime) error {
+			signals <- syscall.SIGTERM
+			<-ctx.Context().Done()
+			return ctx.Context().Err()
+		})).
+		Run([]string{"wait"})
+
+	assertInterrupted(t, err)
+	assertInterrupted(t, cleanupErr)
+}
+
+func TestWorkflow_Run_SignalHandling_GracePeriodExpired(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	release := make(chan struct{})
+	defer close(release)
+	cleanedUp := false
+	err := New().
+		WithSignalHandling(10*time.Millisecond, signals).
+		WithCleanup(func(ctx common.Runtime, err error) {
+			cleanedUp = true
+		}).
+		WithCommands(command.New("stuck").WithAction(func(ctx common.Runtime) error {
+			signals <- os.Interrupt
+			<-release
+			return nil
+		})).
+		Run([]string{"stuck"})
+
+	assertInterrupted(t, err)
+	if !cleanedUp {
+		t.Error("cleanup expected to be executed after grace period")
+	}
+}
+
+func TestWorkflow_Run_SignalHandling_AbandonedAction(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	var observed string
+	err := New().
+		WithSignalHandling(10*time.Millisecond, signals).
+		WithCleanup(func(ctx common.Runtime, err error) {
+			close(release)
+			time.Sleep(10 * time.Millisecond)
+			observed = ctx.CurrentCommand().GetName() + " " + ctx.StringFlag("name")
+		}).
+		WithCommands(command.New("stuck").
+			WithFlags(flag.StringWithDefault("name", "web")).
+			WithAction(func(ctx common.Runtime) error {
+				defer close(finished)
+				signals <- os.Interrupt
+				<-release
+				return nil
+			})).
+		Run([]string{"stuck"})
+	<-finished
+
+	assertInterrupted(t, err)
+	if observed != "stuck web" {
+		t.Error("cleanup expected to observe interrupted command, got:", observed)
+	}
+}
+
+func TestWorkflow_Run_SignalHandling_SecondSignal(t *testing.T) {
+	exitCodes := make(chan int, 1)
+
+	signals := make(chan os.Signal, 2)
+	release := make(chan struct{})
+	w := New().WithSignalHandling(time.Minute, signals)
+	w.(*workflow).signals.exit = func(code int) {
+		exitCodes <- code
+	}
+	err := w.
+		WithCommands(command.New("stuck").WithAction(func(ctx common.Runtime) error {
+			signals <- os.Interrupt
+			signals <- os.Interrupt
+			select {
+			case code := <-exitCodes:
+				if code != ExitInterrupted {
+					t.Error("unexpected exit code:", code)
+				}
+			case <-time.After(time.Second):
+				t.Error("forced exit expected on second signal")
+			}
+			close(release)
+			return nil
+		})).
+		Run([]string{"stuck"})
+	<-release
+	assertInterrupted(t, err)
+}
+
+func assertInterrupted(t *testing.T, err error) {
+	t.Helper()
+	if cErr, ok := err.(common.Error); !ok || cErr.Cause != common.ErrorInterrupted {
+		t.Error("interrupted error expected, got:", err)
+	}
+}
@@ -0,0 +1,219 @@
+package spec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+// builder creates declarations of commands and flags and collects errors with paths of their specifications
+type builder struct {
+	actions Registry
+	errs    []error
+}
+
+func (b *builder) errorAt(path string, err error) {
+	b.errs = append(b.errs, errorAt(path, err))
+}
+
+// flags creates flags, errors of their validation are reported with the path of the flag caused them
+func (b *builder) flags(path string, specs []Flag) []common.Flag {
+	var flags []common.Flag
+	var paths []string
+	for i, spec := range specs {
+		flagPath := indexed(path, i)
+		if f := b.flag(flagPath, spec); f != nil {
+			flags = append(flags, f)
+			paths = append(paths, flagPath)
+		}
+	}
+	common.ValidateEachFlagDeclaration(flags, func(index int, err error) {
+		b.errorAt(paths[index], err)
+	})
+	return flags
+}
+
+func (b *builder) flag(path string, spec Flag) common.Flag {
+	value, err := defaultValue(spec.Type, spec.Default)
+	if err != nil {
+		b.errorAt(join(path, "default"), err)
+	}
+	hasDefault := value != nil
+
+	var f common.Flag
+	switch spec.Type {
+	case TypeString, "":
+		f = flag.String(spec.Name)
+		if hasDefault {
+			f = flag.StringWithDefault(spec.Name, value.(string))
+		}
+	case TypeInt:
+		f = flag.Int(spec.Name)
+		if hasDefault {
+			f = flag.IntWithDefault(spec.Name, value.(int64))
+		}
+	case TypeFloat:
+		f = flag.Float(spec.Name)
+		if hasDefault {
+			f = flag.FloatWithDefault(spec.Name, value.(float64))
+		}
+	case TypeBool:
+		f = flag.Bool(spec.Name)
+		if hasDefault {
+			f = flag.BoolWithDefault(spec.Name, value.(bool))
+		}
+	case TypeDuration:
+		f = flag.Duration(spec.Name)
+		if hasDefault {
+			f = flag.DurationWithDefault(spec.Name, value.(time.Duration))
+		}
+	case TypeSignal:
+		f = flag.Signal(spec.Name)
+	default:
+		b.errorAt(join(path, "type"), errors.New("unsupported type '"+spec.Type+"'"))
+		return nil
+	}
+
+	if spec.Shortcut != "" {
+		shortcut, size := utf8.DecodeRuneInString(spec.Shortcut)
+		if size != len(spec.Shortcut) {
+			b.errorAt(join(path, "shortcut"), common.FlagShortcutInvalidError(spec.Shortcut))
+		} else {
+			f.WithShortcut(shortcut)
+		}
+	}
+	if spec.Required {
+		f.Required(true)
+	}
+	if spec.Description != "" {
+		f.WithDescription(spec.Description)
+	}
+	if spec.Env != "" {
+		f.WithEnv(spec.Env)
+	}
+	if spec.Hidden {
+		f.Hidden()
+	}
+	if spec.Sensitive {
+		f.Sensitive()
+	}
+	return f
+}
+
+// defaultValue converts `value` into the value of the flag type, 'nil' means there is no default
+func defaultValue(typ string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	original := value
+	if number, ok := value.(json.Number); ok {
+		value = numberValue(number)
+	}
+	switch typ {
+	case TypeString, "":
+		switch v := original.(type) {
+		case string:
+			return v, nil
+		case bool, int64, float64, json.Number:
+			return fmt.Sprint(v), nil
+		}
+	case TypeInt:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int64(v), nil
+			}
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		}
+	case TypeFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			return strconv.ParseFloat(v, 64)
+		}
+	case TypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case TypeDuration:
+		if v, ok := value.(string); ok {
+			return time.ParseDuration(v)
+		}
+	case TypeSignal:
+		return nil, errors.New("signal flag can't have default value")
+	default:
+		// unsupported type is reported by the caller
+		return nil, nil
+	}
+	return nil, fmt.Errorf("invalid default value of type '%s': %v", typ, original)
+}
+
+// numberValue converts JSON number into `int64` if it is an integer in its range and into `float64` otherwise
+func numberValue(number json.Number) interface{} {
+	if i, err := number.Int64(); err == nil {
+		return i
+	}
+	if f, err := number.Float64(); err == nil {
+		return f
+	}
+	return number.String()
+}
+
+// commands creates commands, errors of their validation are reported with the path of the command caused them
+// Errors of flags and child commands are reported with their own paths when they are created
+func (b *builder) commands(path string, specs []Command) []common.CommandDeclaration {
+	var commands []common.CommandDeclaration
+	for i, spec := range specs {
+		commands = append(commands, b.command(indexed(path, i), spec))
+	}
+	common.ValidateEachCommandDeclaration(commands, func(index int, err error, declared bool) {
+		if !declared {
+			b.errorAt(indexed(path, index), err)
+		}
+	})
+	return commands
+}
+
+func (b *builder) command(path string, spec Command) common.CommandDeclaration {
+	cmd := command.New(spec.Name)
+	for _, err := range cmd.GetDeclarationErrors() {
+		b.errorAt(join(path, "name"), err)
+	}
+	if spec.Description != "" {
+		cmd.WithDescription(spec.Description)
+	}
+	if spec.Hidden {
+		cmd.Hidden()
+	}
+	if spec.Action != "" {
+		if action := b.actions[spec.Action]; action != nil {
+			cmd.WithAction(action)
+		} else {
+			b.errorAt(join(path, "action"), common.ActionInvalidError("action '"+spec.Action+"' is not registered"))
+		}
+	}
+	if flags := b.flags(join(path, "flags"), spec.Flags); len(flags) != 0 {
+		cmd.WithFlags(flags...)
+	}
+	if commands := b.commands(join(path, "commands"), spec.Commands); len(commands) != 0 {
+		cmd.WithSubCommands(commands...)
+	}
+	return cmd
+}
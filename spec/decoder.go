@@ -0,0 +1,119 @@
+package spec
+
+import (
+	"errors"
+	"sort"
+)
+
+// decoder converts generic document parsed from JSON or YAML into specification
+// Errors are collected with paths of invalid elements
+type decoder struct {
+	errs []error
+}
+
+func (d *decoder) errorAt(path string, err error) {
+	d.errs = append(d.errs, errorAt(path, err))
+}
+
+func (d *decoder) spec(document interface{}) Spec {
+	var spec Spec
+	d.fields("", document, map[string]func(path string, value interface{}){
+		"name":        func(path string, value interface{}) { spec.Name = d.string(path, value) },
+		"description": func(path string, value interface{}) { spec.Description = d.string(path, value) },
+		"flags":       func(path string, value interface{}) { spec.Flags = d.flags(path, value) },
+		"commands":    func(path string, value interface{}) { spec.Commands = d.commands(path, value) },
+	})
+	return spec
+}
+
+func (d *decoder) commands(path string, value interface{}) []Command {
+	var commands []Command
+	for i, item := range d.list(path, value) {
+		var cmd Command
+		d.fields(indexed(path, i), item, map[string]func(path string, value interface{}){
+			"name":        func(path string, value interface{}) { cmd.Name = d.string(path, value) },
+			"description": func(path string, value interface{}) { cmd.Description = d.string(path, value) },
+			"action":      func(path string, value interface{}) { cmd.Action = d.string(path, value) },
+			"hidden":      func(path string, value interface{}) { cmd.Hidden = d.bool(path, value) },
+			"flags":       func(path string, value interface{}) { cmd.Flags = d.flags(path, value) },
+			"commands":    func(path string, value interface{}) { cmd.Commands = d.commands(path, value) },
+		})
+		commands = append(commands, cmd)
+	}
+	return commands
+}
+
+func (d *decoder) flags(path string, value interface{}) []Flag {
+	var flags []Flag
+	for i, item := range d.list(path, value) {
+		var f Flag
+		d.fields(indexed(path, i), item, map[string]func(path string, value interface{}){
+			"name":        func(path string, value interface{}) { f.Name = d.string(path, value) },
+			"shortcut":    func(path string, value interface{}) { f.Shortcut = d.string(path, value) },
+			"type":        func(path string, value interface{}) { f.Type = d.string(path, value) },
+			"default":     func(path string, value interface{}) { f.Default = value },
+			"required":    func(path string, value interface{}) { f.Required = d.bool(path, value) },
+			"description": func(path string, value interface{}) { f.Description = d.string(path, value) },
+			"env":         func(path string, value interface{}) { f.Env = d.string(path, value) },
+			"hidden":      func(path string, value interface{}) { f.Hidden = d.bool(path, value) },
+			"sensitive":   func(path string, value interface{}) { f.Sensitive = d.bool(path, value) },
+		})
+		flags = append(flags, f)
+	}
+	return flags
+}
+
+// fields calls decoder of each entry of the mapping `value` in order of keys, unknown keys are reported
+func (d *decoder) fields(path string, value interface{}, decoders map[string]func(path string, value interface{})) {
+	entries, ok := value.(map[string]interface{})
+	if !ok {
+		d.errorAt(path, errors.New("mapping expected"))
+		return
+	}
+	var keys []string
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		decode, found := decoders[key]
+		if !found {
+			d.errorAt(join(path, key), errors.New("unknown key"))
+			continue
+		}
+		decode(join(path, key), entries[key])
+	}
+}
+
+func (d *decoder) list(path string, value interface{}) []interface{} {
+	if value == nil {
+		return nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		d.errorAt(path, errors.New("list expected"))
+	}
+	return items
+}
+
+func (d *decoder) string(path string, value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		d.errorAt(path, errors.New("string expected"))
+	}
+	return s
+}
+
+func (d *decoder) bool(path string, value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	b, ok := value.(bool)
+	if !ok {
+		d.errorAt(path, errors.New("boolean expected"))
+	}
+	return b
+}
@@ -0,0 +1,213 @@
+// Package spec builds workflow from JSON or YAML specification of its commands and flags
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/internal/yaml"
+)
+
+// Types of flags supported in specification
+const (
+	TypeString   = "string"
+	TypeInt      = "int"
+	TypeFloat    = "float"
+	TypeBool     = "bool"
+	TypeDuration = "duration"
+	TypeSignal   = "signal"
+)
+
+// Registry maps names of actions used in specification to their implementations
+type Registry map[string]func(ctx common.Runtime) error
+
+// Spec describes the workflow
+type Spec struct {
+	// Name of the application, base name of the executable is used if empty
+	Name string
+	// Description of the application
+	Description string
+	// Flags are global flags of the workflow
+	Flags []Flag
+	// Commands of the workflow
+	Commands []Command
+}
+
+// Command describes command of the workflow
+type Command struct {
+	// Name of the command
+	Name string
+	// Description of the command
+	Description string
+	// Action is a name of the action in `Registry` executed as a main task of the command
+	Action string
+	// Hidden command is omitted from help and documentation
+	Hidden bool
+	// Flags of the command
+	Flags []Flag
+	// Commands are child commands
+	Commands []Command
+}
+
+// Flag describes flag of the command or global flag
+type Flag struct {
+	// Name of the flag
+	Name string
+	// Shortcut is a single character short name of the flag
+	Shortcut string
+	// Type of the flag value, one of `TypeString` (used if empty), `TypeInt`, `TypeFloat`, `TypeBool`, `TypeDuration` or `TypeSignal`
+	Type string
+	// Default value of the flag, 'nil' if flag has no default
+	Default interface{}
+	// Required flag must be provided
+	Required bool
+	// Description of the flag
+	Description string
+	// Env is a name of the environment variable used as a value of the flag
+	Env string
+	// Hidden flag is omitted from help and documentation
+	Hidden bool
+	// Sensitive flag value is a secret
+	Sensitive bool
+}
+
+// Error is an error found in specification at `Path`, e.g. 'commands[1].flags[0]'
+type Error struct {
+	// Path of the element of specification, empty for the root
+	Path string
+	// Err describes the problem
+	Err error
+}
+
+// Error returns string representation of the error prefixed with path
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns error of the element
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Load builds workflow from the specification file, see `Parse`
+func Load(path string, actions Registry) (stalk.Workflow, error) {
+	spec, err := Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	return Build(spec, actions)
+}
+
+// FromJSON builds workflow from JSON specification
+func FromJSON(data []byte, actions Registry) (stalk.Workflow, error) {
+	spec, err := ParseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return Build(spec, actions)
+}
+
+// FromYAML builds workflow from YAML specification
+func FromYAML(data []byte, actions Registry) (stalk.Workflow, error) {
+	spec, err := ParseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	return Build(spec, actions)
+}
+
+// Parse reads specification file, files with '.json' extension are parsed as JSON, others as YAML
+func Parse(path string) (Spec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Spec{}, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseJSON(data)
+	}
+	return ParseYAML(data)
+}
+
+// ParseJSON parses JSON specification
+// Errors of the document structure are returned as `common.DeclarationErrors` of `Error` values
+// Numbers are decoded as `json.Number`, so integer default values are not rounded
+func ParseJSON(data []byte) (Spec, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return Spec{}, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return Spec{}, errors.New("invalid JSON: unexpected data after specification")
+	}
+	return decode(document)
+}
+
+// ParseYAML parses YAML specification
+// Errors of the document structure are returned as `common.DeclarationErrors` of `Error` values
+func ParseYAML(data []byte) (Spec, error) {
+	document, err := yaml.Unmarshal(data)
+	if err != nil {
+		return Spec{}, err
+	}
+	return decode(document)
+}
+
+func decode(document interface{}) (Spec, error) {
+	d := &decoder{}
+	spec := d.spec(document)
+	if len(d.errs) != 0 {
+		return Spec{}, common.DeclarationErrors(d.errs)
+	}
+	return spec, nil
+}
+
+// Build creates workflow described by `spec` with actions found in `actions` by name
+// Errors are returned as `common.DeclarationErrors` of `Error` values with paths of invalid elements
+func Build(spec Spec, actions Registry) (stalk.Workflow, error) {
+	b := &builder{actions: actions}
+	flags := b.flags("flags", spec.Flags)
+	commands := b.commands("commands", spec.Commands)
+
+	w := stalk.New().WithDescription(spec.Description).WithGlobalFlags(flags...).WithCommands(commands...)
+	if spec.Name != "" {
+		w.WithName(spec.Name)
+	}
+	if len(b.errs) != 0 {
+		return nil, common.DeclarationErrors(b.errs)
+	}
+	// all errors are expected to be found by the builder, it is a safety net
+	if errs := w.GetDeclarationErrors(); len(errs) != 0 {
+		return nil, common.DeclarationErrors(errs)
+	}
+	return w, nil
+}
+
+// join returns path of the element `name` nested into `path`
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// indexed returns path of the element of the list at `path`
+func indexed(path string, index int) string {
+	return path + "[" + strconv.Itoa(index) + "]"
+}
+
+func errorAt(path string, err error) error {
+	return Error{Path: path, Err: err}
+}
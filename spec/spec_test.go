@@ -0,0 +1,166 @@
+package spec
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pavelmemory/stalk"
+	"github.com/pavelmemory/stalk/common"
+)
+
+const specJSON = `{
+  "name": "app",
+  "description": "manages servers",
+  "flags": [{"name": "verbose", "shortcut": "v", "type": "signal"}],
+  "commands": [
+    {
+      "name": "server",
+      "commands": [
+        {
+          "name": "create",
+          "action": "create",
+          "flags": [
+            {"name": "name", "required": true},
+            {"name": "size", "type": "int", "default": 2},
+            {"name": "timeout", "type": "duration", "default": "1m"}
+          ]
+        }
+      ]
+    }
+  ]
+}`
+
+const specYAML = `
+name: app
+description: manages servers
+flags:
+  - name: verbose
+    shortcut: v
+    type: signal
+commands:
+  - name: server
+    commands:
+      - name: create
+        action: create
+        flags:
+          - name: name
+            required: true
+          - {name: size, type: int, default: 2}
+          - name: timeout
+            type: duration
+            default: 1m
+`
+
+func TestBuild(t *testing.T) {
+	var executed []string
+	actions := Registry{
+		"create": func(ctx common.Runtime) error {
+			executed = append(executed, ctx.StringFlag("name")+" "+ctx.DurationFlag("timeout").String())
+			return ctx.Render(ctx.IntFlag("size"))
+		},
+	}
+
+	for index, scenario := range []struct {
+		load func() (stalk.Workflow, error)
+	}{
+		/*1*/ {func() (stalk.Workflow, error) { return FromJSON([]byte(specJSON), actions) }},
+		/*2*/ {func() (stalk.Workflow, error) { return FromYAML([]byte(specYAML), actions) }},
+	} {
+		w, err := scenario.load()
+		if err != nil {
+			t.Error("index:", index+1, "\nunexpected error:\n", err)
+			continue
+		}
+
+		executed = nil
+		out := &bytes.Buffer{}
+		w.WithIO(strings.NewReader(""), out, &bytes.Buffer{})
+		if err := w.Run([]string{"-v", "server", "create", "--name", "web"}); err != nil {
+			t.Error("index:", index+1, "\nunexpected error:\n", err)
+			continue
+		}
+		if expected := []string{"web 1m0s"}; !reflect.DeepEqual(expected, executed) {
+			t.Error("index:", index+1, "\nexpected:\n", expected, "\nactual:\n", executed)
+		}
+		if expected, actual := "2\n", out.String(); expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", expected, "\nactual:\n", actual)
+		}
+		if expected, actual := "app", w.GetDeclaredName(); expected != actual {
+			t.Error("index:", index+1, "\nexpected:\n", expected, "\nactual:\n", actual)
+		}
+	}
+}
+
+func TestParseJSON_Numbers(t *testing.T) {
+	var actual []string
+	w, err := FromJSON([]byte(`{"commands": [{"name": "print", "action": "print", "flags": [
+		{"name": "id", "type": "int", "default": 9007199254740993},
+		{"name": "ratio", "type": "float", "default": 0.25},
+		{"name": "label", "default": 1.50}
+	]}]}`), Registry{"print": func(ctx common.Runtime) error {
+		actual = []string{strconv.FormatInt(ctx.IntFlag("id"), 10), strconv.FormatFloat(ctx.FloatFlag("ratio"), 'g', -1, 64), ctx.StringFlag("label")}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Run([]string{"print"}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"9007199254740993", "0.25", "1.50"}; !reflect.DeepEqual(expected, actual) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+
+	if _, err := ParseJSON([]byte(`{"name": "app"} {}`)); err == nil {
+		t.Error("error expected for data after specification")
+	}
+}
+
+func TestBuild_Errors(t *testing.T) {
+	actions := Registry{"print": func(ctx common.Runtime) error { return nil }}
+
+	for index, scenario := range []struct {
+		spec     string
+		expected []string
+	}{
+		/*1*/ {`{"commands": [{"name": "a", "action": "print"}, {"name": "b", "action": "print", "flags": [{"name": "x"}, {"name": "x"}]}]}`,
+			[]string{"commands[1].flags[1]: flag name is not unique: [--x]? [STRING]"}},
+		/*2*/ {`{"commands": [{"name": "a", "action": "print"}, {"name": "a", "action": "print"}]}`,
+			[]string{"commands[1]: command name is not unique: a"}},
+		/*3*/ {`{"commands": [{"name": "a", "action": "print"}, {"name": "a", "action": "print", "flags": [{"name": "x"}, {"name": "x", "shortcut": "y"}, {"name": "z", "shortcut": "y"}]}]}`,
+			[]string{"commands[1].flags[1]: flag name is not unique: [--x|-y]? [STRING]",
+				"commands[1].flags[2]: flag shortcut is not unique: [--z|-y]? [STRING]",
+				"commands[1]: command name is not unique: a"}},
+		/*4*/ {`{"commands": [{"name": "a", "commands": [{"name": "b", "action": "missing"}]}]}`,
+			[]string{"commands[0].commands[0].action: invalid action: action 'missing' is not registered",
+				"commands[0].commands[0]: invalid action: command 'b' has no action neither sub-commands to execute"}},
+		/*5*/ {`{"flags": [{"name": "n", "type": "number"}, {"name": "size", "type": "int", "default": 1.5}, {"name": "s", "shortcut": "ab"}]}`,
+			[]string{"flags[0].type: unsupported type 'number'",
+				"flags[1].default: invalid default value of type 'int': 1.5",
+				"flags[2].shortcut: invalid flag shortcut: ab"}},
+		/*7*/ {`{"flags": [{"name": "a", "type": "int", "default": 9223372036854775808}, {"name": "b", "type": "int", "default": 1e300}, {"name": "c", "type": "bool", "default": 1}]}`,
+			[]string{"flags[0].default: invalid default value of type 'int': 9223372036854775808",
+				"flags[1].default: invalid default value of type 'int': 1e300",
+				"flags[2].default: invalid default value of type 'bool': 1"}},
+		/*6*/ {`{"commands": [{"name": "a", "action": "print", "alias": "b", "hidden": "yes", "flags": {}}], "extra": 1}`,
+			[]string{"commands[0].alias: unknown key", "commands[0].flags: list expected", "commands[0].hidden: boolean expected", "extra: unknown key"}},
+	} {
+		_, err := FromJSON([]byte(scenario.spec), actions)
+		var declErrs common.DeclarationErrors
+		if !errors.As(err, &declErrs) {
+			t.Error("index:", index+1, "\nunexpected error:\n", err)
+			continue
+		}
+		var actual []string
+		for _, declErr := range declErrs {
+			actual = append(actual, declErr.Error())
+		}
+		if !reflect.DeepEqual(scenario.expected, actual) {
+			t.Error("index:", index+1, "\nexpected:\n", strings.Join(scenario.expected, "\n"), "\nactual:\n", strings.Join(actual, "\n"))
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package stalk
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+)
+
+type deployOptions struct {
+	Region   string        `flag:"" short:"r" default:"eu-west" env:"STALK_TEST_REGION" description:"region of the deployment"`
+	Replicas int8          `flag:"" default:"1"`
+	Ratio    float64       `flag:"canary-ratio"`
+	Wait     time.Duration `flag:"" default:"30s"`
+	DryRun   bool          `flag:"" short:"n"`
+	Token    string        `flag:"" required:"true" sensitive:"true" env:"STALK_TEST_TOKEN"`
+	internal string
+
+	Rollback rollbackOptions `command:"" description:"rollback the deployment"`
+	Status   *statusOptions  `command:"state"`
+}
+
+type rollbackOptions struct {
+	Version string `flag:"" required:"true"`
+	Force   bool   `flag:"" default:"false"`
+
+	ran []string
+}
+
+func (r *rollbackOptions) Run(ctx common.Runtime) error {
+	r.ran = append(r.ran, r.Version)
+	return nil
+}
+
+type statusOptions struct {
+	HTTPPort int      `flag:""`
+	Targets  []string `args:""`
+}
+
+func TestFromStruct(t *testing.T) {
+	var opts deployOptions
+	deploy := command.FromStruct("deploy", &opts)
+	if errs := deploy.GetDeclarationErrors(); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	var flags []string
+	for _, f := range deploy.GetDeclaredFlags() {
+		flags = append(flags, f.String())
+	}
+	expected := []string{
+		"[--region|-r]? <STRING, eu-west>",
+		"[--replicas]? <INT, 1>",
+		"[--canary-ratio]? [FLOAT]",
+		"[--wait]? <DURATION, 30s>",
+		"[--dry-run|-n]?",
+		"[--token] [STRING]",
+	}
+	if !reflect.DeepEqual(expected, flags) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", flags)
+	}
+
+	var subCommands []string
+	for _, cmd := range deploy.GetDeclaredSubCommands() {
+		subCommands = append(subCommands, cmd.GetName()+": "+cmd.GetDeclaredDescription()+" "+cmd.GetDeclaredFlags()[0].String())
+	}
+	expected = []string{"rollback: rollback the deployment [--version] [STRING]", "state:  [--http-port]? [INT]"}
+	if !reflect.DeepEqual(expected, subCommands) {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", subCommands)
+	}
+	if opts.Status == nil {
+		t.Error("pointer to struct of the command must be allocated")
+	}
+
+	workflow := New().WithIO(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{}).WithCommands(deploy)
+
+	t.Setenv("STALK_TEST_TOKEN", "secret")
+	if err := workflow.Run([]string{"deploy", "-r", "us-east", "--canary-ratio", "0.5", "-n", "state", "--http-port", "80", "web", "db"}); err != nil {
+		t.Fatal(err)
+	}
+	expectedOpts := deployOptions{
+		Region: "us-east", Replicas: 1, Ratio: 0.5, Wait: 30 * time.Second, DryRun: true, Token: "secret",
+		Status: &statusOptions{HTTPPort: 80, Targets: []string{"web", "db"}},
+	}
+	if !reflect.DeepEqual(expectedOpts, opts) {
+		t.Errorf("\nexpected:\n%+v\nactual:\n%+v", expectedOpts, opts)
+	}
+
+	// values of previous execution are not kept
+	t.Setenv("STALK_TEST_REGION", "ap-south")
+	if err := workflow.Run([]string{"deploy", "--token", "other", "--wait", "1m", "rollback", "--version", "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	expectedOpts = deployOptions{
+		Region: "ap-south", Replicas: 1, Wait: time.Minute, Token: "other",
+		Rollback: rollbackOptions{Version: "v2", ran: []string{"v2"}}, Status: opts.Status,
+	}
+	if !reflect.DeepEqual(expectedOpts, opts) {
+		t.Errorf("\nexpected:\n%+v\nactual:\n%+v", expectedOpts, opts)
+	}
+
+	err := workflow.Run([]string{"deploy", "--replicas", "1000"})
+	var stalkErr common.Error
+	if !errors.As(err, &stalkErr) || stalkErr.Cause != common.ErrorFlagValueInvalid {
+		t.Error("unexpected error:", err)
+	}
+}
+
+func TestFromStruct_DeclarationErrors(t *testing.T) {
+	for index, scenario := range []struct {
+		options  interface{}
+		expected string
+	}{
+		/*1*/ {deployOptions{}, "invalid struct declaration: pointer to struct expected for command 'cmd': struct"},
+		/*2*/ {&struct {
+			Values []int `flag:""`
+		}{}, "invalid struct declaration: field 'Values' of command 'cmd': unsupported flag type: []int"},
+		/*3*/ {&struct {
+			Count int `flag:"" default:"many"`
+		}{}, "invalid struct declaration: field 'Count' of command 'cmd': default value: strconv.ParseInt: parsing \"many\": invalid syntax"},
+		/*4*/ {&struct {
+			Name string `flag:"" short:"nm"`
+		}{}, "invalid struct declaration: field 'Name' of command 'cmd': shortcut must be a single character: nm"},
+		/*5*/ {&struct {
+			Args string `args:""`
+		}{}, "invalid struct declaration: field 'Args' of command 'cmd': arguments require '[]string' type"},
+		/*6*/ {&struct {
+			Sub string `command:""`
+		}{}, "invalid struct declaration: field 'Sub' of command 'cmd': command requires struct type: string"},
+		/*7*/ {&struct {
+			Name string `flag:"" required:"yes please"`
+		}{}, "invalid struct declaration: field 'Name' of command 'cmd': tag 'required': strconv.ParseBool: parsing \"yes please\": invalid syntax"},
+		/*8*/ {&struct {
+			Name string `flag:"" required:"true" default:"x"`
+		}{}, "required flag has default value: name"},
+	} {
+		errs := command.FromStruct("cmd", scenario.options).GetDeclarationErrors()
+		if len(errs) != 1 || errs[0].Error() != scenario.expected {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", errs)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package stalk
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pavelmemory/stalk/command"
+	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/flag"
+)
+
+func TestWorkflow_Run_CommandTimeout(t *testing.T) {
+	var mu sync.Mutex
+	hookErrs := map[string]error{}
+	record := func(name string) func(common.Runtime, error) {
+		return func(ctx common.Runtime, err error) {
+			mu.Lock()
+			hookErrs[name] = err
+			mu.Unlock()
+			if ctx.Context().Err() != nil {
+				t.Error(name, "hooks are not expected to be limited by timeout")
+			}
+		}
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	err := New().
+		WithCommands(command.New("slow").
+			WithTimeout(10 * time.Millisecond).
+			WithAction(func(ctx common.Runtime) error {
+				<-release
+				return nil
+			}).
+			WithOnError(record("command OnError")).
+			WithAfter(record("command After"))).
+		WithOnError(record("OnError")).
+		WithCleanup(record("Cleanup")).
+		Run([]string{"slow"})
+
+	assertErrorCause(t, err, common.ErrorTimeout)
+	mu.Lock()
+	defer mu.Unlock()
+	for _, name := range []string{"command OnError", "command After", "OnError", "Cleanup"} {
+		assertErrorCause(t, hookErrs[name], common.ErrorTimeout)
+	}
+}
+
+func TestWorkflow_Run_ActionOutlivesTimeout(t *testing.T) {
+	finished := make(chan string, 1)
+	err := New().
+		WithCommands(command.New("slow").
+			WithFlags(flag.StringWithDefault("name", "web")).
+			WithTimeout(10 * time.Millisecond).
+			WithAction(func(ctx common.Runtime) error {
+				time.Sleep(30 * time.Millisecond)
+				observed := ctx.StringFlag("name") + " " + ctx.CurrentCommand().GetName()
+				ctx.Set("slow", true)
+				finished <- observed
+				return nil
+			}).
+			WithAfter(func(ctx common.Runtime, err error) {
+				ctx.Set("after", true)
+			})).
+		WithCleanup(func(ctx common.Runtime, err error) {
+			ctx.Get("slow")
+		}).
+		Run([]string{"slow"})
+
+	assertErrorCause(t, err, common.ErrorTimeout)
+	if actual := <-finished; actual != "web slow" {
+		t.Error("abandoned action expected to observe its command, got:", actual)
+	}
+}
+
+func TestWorkflow_Run_GlobalTimeoutFlag(t *testing.T) {
+	wf := New().
+		WithGlobalFlags(flag.Duration("timeout")).
+		WithTimeoutFlag("timeout").
+		WithTimeout(time.Hour).
+		WithCommands(command.New("wait").WithAction(func(ctx common.Runtime) error {
+			<-ctx.Context().Done()
+			return ctx.Context().Err()
+		}))
+
+	err := wf.Run([]string{"--timeout", "10ms", "wait"})
+	assertErrorCause(t, err, common.ErrorTimeout)
+}
+
+func TestWorkflow_Run_TimeoutNotExceeded(t *testing.T) {
+	err := New().
+		WithTimeout(time.Minute).
+		WithCommands(command.New("fast").WithTimeout(time.Minute).WithAction(emptyAction)).
+		Run([]string{"fast"})
+	if err != nil {
+		t.Error("no error expected, got:", err)
+	}
+}
+
+func assertErrorCause(t *testing.T, err error, expected common.ErrorCode) {
+	t.Helper()
+	if cErr, ok := err.(common.Error); !ok || cErr.Cause != expected {
+		t.Error("error caused by", expected, "expected, got:", err)
+	}
+}
@@ -1,8 +1,19 @@
 package stalk
 
 import (
+	stdcontext "context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/pavelmemory/stalk/common"
+	"github.com/pavelmemory/stalk/context"
 	"github.com/pavelmemory/stalk/flag"
+	"github.com/pavelmemory/stalk/help"
+	"github.com/pavelmemory/stalk/prompt"
+	"github.com/pavelmemory/stalk/render"
 )
 
 // Workflow describes tree-like structure representation of commands with flags and trigger-callbacks
@@ -23,8 +34,34 @@ type Workflow interface {
 	// Run parses provided slice of strings that represents commands, flags, flag values and command arguments
 	// and runs founded commands with founded or default flag values
 	Run(cmd []string) error
+	// RunContext does the same as `Run`, but all actions observe provided `ctx` through `Runtime.Context`
+	// so they can be cancelled or limited with deadline
+	RunContext(ctx stdcontext.Context, cmd []string) error
+	// Shell starts interactive session that reads command lines from the input stream and executes them one by one
+	// `Setup` is executed once at the start and `Cleanup` once at the end, values stored in runtime are shared by commands
+	// Errors of the lines are printed into error output stream, `OnError` is executed for each of them
+	// Built-in command 'exit' ends the session as well as end of the input, 'help [command...]' prints help
+	// If input is a terminal, line can be edited, history is available with arrows and tab completes commands and flags
+	Shell() error
+	// ShellContext does the same as `Shell`, but the session ends when `ctx` is cancelled
+	ShellContext(ctx stdcontext.Context) error
+	// RunScript executes command lines read from `script` one by one
+	// `Setup` is executed once at the start and `Cleanup` once at the end, values stored in runtime are shared by commands
+	// Lines starting with '#' are comments, line ending with '\' continues on the next line
+	// Line is split into arguments first, then '${name}' in each argument is replaced with the value stored in runtime
+	// by key 'name', so the value stays a part of the same argument regardless of its content
+	// Variables in single quotes or escaped, e.g. '\${name}', are not replaced
+	// Error of the line is returned as `common.LineError`, see `WithScriptErrorPolicy` for the lines after it,
+	// `OnError` is executed once for each failed line
+	// Commands are not interactive: missing required flags are not prompted and confirmations are not asked
+	RunScript(script io.Reader) error
+	// RunScriptContext does the same as `RunScript`, but the execution stops when `ctx` is cancelled
+	RunScriptContext(ctx stdcontext.Context, script io.Reader) error
 	// GetDeclarationErrors returns errors found in declarations of global flags, commands and command flags after 'Run' execution
 	GetDeclarationErrors() []error
+	// Describe returns serializable description of the declared global flags and commands, hidden ones included
+	// It can be consumed by other tools, see `Schema.WriteJSON`
+	Describe() Schema
 	// WithCleanup sets function that will be executed only once after last command
 	// You may use it for operations such as closing connection to database, etc...
 	WithCleanup(func(ctx common.Runtime, err error)) Workflow
@@ -43,68 +80,297 @@ type Workflow interface {
 	// Returns default help flag is user-specific flag was not set
 	// Default help flag has name 'help' and shortcut 'h'
 	GetDeclaredHelpFlag() common.Flag
+	// WithHelpPrinter sets printer used to print help information when help flag found
+	WithHelpPrinter(printer help.Printer) Workflow
+	// GetDeclaredHelpPrinter returns printer used to print help information
+	// Default printer writes to the declared output stream and detects width of the terminal
+	GetDeclaredHelpPrinter() help.Printer
+	// WithName sets name of the application used in help information
+	WithName(name string) Workflow
+	// GetDeclaredName returns name of the application
+	// Default name is a base name of the executable
+	GetDeclaredName() string
+	// WithDescription sets logical description of the application
+	WithDescription(value string) Workflow
+	// GetDeclaredDescription returns description of the application
+	GetDeclaredDescription() string
+	// WithWarningSink sets function that receives warnings, e.g. about usage of deprecated flags and commands
+	// if nil provided warnings are discarded
+	WithWarningSink(sink func(warning string)) Workflow
+	// GetDeclaredWarningSink returns function that receives warnings
+	// Default sink writes warnings to the declared error output stream
+	GetDeclaredWarningSink() func(warning string)
+	// WithSignalHandling enables trapping of SIGINT and SIGTERM signals during `Run`
+	// First signal cancels runtime context and gives actions `gracePeriod` to finish,
+	// after that `OnError` and `Cleanup` are executed with error caused by `ErrorInterrupted`
+	// Setup or action that didn't finish in grace period is abandoned: it keeps running concurrently with
+	// `OnError` and `Cleanup`, so resources released by them may still be in use
+	// Hooks get a snapshot of the runtime that is not changed by the abandoned execution
+	// Second signal forces exit of the process
+	// If `signals` is not nil it is used as a source of signals instead of the operating system
+	WithSignalHandling(gracePeriod time.Duration, signals <-chan os.Signal) Workflow
+	// GetDeclaredSignalHandling returns grace period given to actions on interruption
+	// and `true` if signal handling is enabled
+	GetDeclaredSignalHandling() (gracePeriod time.Duration, enabled bool)
+	// WithTimeout limits execution time of all commands
+	// Actions that don't complete in time are abandoned and execution ends with `ErrorTimeout` error
+	// Abandoned action keeps running with a snapshot of the runtime that is not changed by the following hooks
+	WithTimeout(timeout time.Duration) Workflow
+	// GetDeclaredTimeout returns execution time limit of all commands, 0 means no limit
+	GetDeclaredTimeout() time.Duration
+	// WithTimeoutFlag sets name of the global flag created with `flag.Duration` which value overrides declared timeout
+	WithTimeoutFlag(name string) Workflow
+	// GetDeclaredTimeoutFlag returns name of the global flag that overrides declared timeout
+	GetDeclaredTimeoutFlag() string
+	// WithPanicRecovery enables or disables conversion of panics in actions into errors caused by `ErrorPanic`
+	// Such errors are handled by `OnError`, `After` and `Cleanup` actions as any other error
+	// It may be useful to disable it for debugging
+	WithPanicRecovery(enabled bool) Workflow
+	// GetDeclaredPanicRecovery returns `true` if panics are converted into errors, it is enabled by default
+	GetDeclaredPanicRecovery() bool
+	// Use adds middlewares applied to the main task of each command
+	// Workflow middlewares wrap middlewares of the commands, first added middleware is the outermost one
+	Use(middlewares ...common.Middleware) Workflow
+	// GetDeclaredMiddlewares returns middlewares added to the workflow
+	GetDeclaredMiddlewares() []common.Middleware
+	// WithIO sets streams used instead of standard input, output and error output
+	// by actions through `Runtime`, by help printer, warning sink and `Main`
+	// Streams passed as 'nil' are left unchanged
+	WithIO(in io.Reader, out io.Writer, errOut io.Writer) Workflow
+	// GetDeclaredIO returns input, output and error output streams of the workflow
+	GetDeclaredIO() (in io.Reader, out io.Writer, errOut io.Writer)
+	// WithRenderer sets renderer that provides output formats used by `Runtime.Render`
+	// if nil provided default renderer is used
+	WithRenderer(renderer render.Renderer) Workflow
+	// GetDeclaredRenderer returns renderer that provides output formats
+	// Default renderer supports table, JSON, YAML, CSV and template formats
+	GetDeclaredRenderer() render.Renderer
+	// WithOutputFlag sets global flag created with `flag.String` which value selects output format of `Runtime.Render`
+	// Declared global flag with the same name is used instead of it, so it can be redefined
+	// if nil provided default format of the renderer is always used
+	WithOutputFlag(output common.Flag) Workflow
+	// GetDeclaredOutputFlag returns global flag that selects output format
+	// Default output flag has name 'output' and shortcut 'o'
+	GetDeclaredOutputFlag() common.Flag
+	// WithPrompter enables prompting for values of required flags missing in command line
+	// User is asked only if prompter is interactive, e.g. `prompt.Terminal` created for the terminal input
+	// if nil provided prompting is disabled, it is disabled by default
+	WithPrompter(prompter prompt.Prompter) Workflow
+	// GetDeclaredPrompter returns prompter used to ask for values of missing required flags
+	GetDeclaredPrompter() prompt.Prompter
+	// WithScriptFlag sets global flag which value is a path of the script executed with `RunScript` instead of the command
+	// Value '-' reads the script from the input stream, the flag can't be combined with other arguments
+	// Declared global flag with the same name is used instead of it, so it can be redefined
+	// if nil provided scripts can't be executed from the command line
+	WithScriptFlag(script common.Flag) Workflow
+	// GetDeclaredScriptFlag returns global flag which value is a path of the script
+	// Default script flag has name 'script'
+	GetDeclaredScriptFlag() common.Flag
+	// WithScriptErrorPolicy sets whether execution of the script continues after the line that ended with an error
+	// If it continues, errors of all failed lines are returned as `common.LineErrors`
+	WithScriptErrorPolicy(policy ScriptErrorPolicy) Workflow
+	// GetDeclaredScriptErrorPolicy returns whether execution of the script continues after the failed line
+	// Default policy is `StopOnError`
+	GetDeclaredScriptErrorPolicy() ScriptErrorPolicy
+	// WithResponseFiles enables expansion of arguments '@path' in `Run` into arguments read from the file located by 'path'
+	// Arguments in the file are separated by whitespaces and line breaks and quoted the same way as in shell,
+	// lines starting with '#' are comments, files may refer to other response files relative to their location
+	// Argument '@@text' is passed as '@text' without expansion
+	WithResponseFiles(enabled bool) Workflow
+	// GetDeclaredResponseFiles returns `true` if response files are expanded, it is disabled by default
+	GetDeclaredResponseFiles() bool
+	// WithPlugins enables execution of unknown top-level commands by external executables named '<name>-<command>',
+	// where 'name' is a name of the application, e.g. 'app-deploy' for command 'deploy' of 'app'
+	// Executables are looked up in `dirs` first and then in directories of 'PATH' environment variable
+	// Arguments after the command are passed to the executable as is, values of the found global flags are passed
+	// through environment variables: declared by `common.Flag.WithEnv` or '<NAME>_<FLAG>' otherwise, e.g. 'APP_VERBOSE'
+	// Plugins are listed in help of the application
+	WithPlugins(enabled bool, dirs ...string) Workflow
+	// GetDeclaredPlugins returns directories plugins are looked up in before 'PATH' and `true` if plugins are enabled
+	GetDeclaredPlugins() (dirs []string, enabled bool)
 }
 
 // creates new workflow that needs to be tuned with flags and commands
 func New() Workflow {
-	return &workflow{
-		helpFlag: flag.Signal("help").WithShortcut('h'),
+	w := &workflow{
+		name:     filepath.Base(os.Args[0]),
+		helpFlag: flag.Signal("help").WithShortcut('h').WithDescription("show help information"),
+		outputFlag: flag.StringWithDefault("output", render.FormatTable).WithShortcut('o').
+			WithDescription("output format: table, json, yaml, csv or template=TEXT"),
+		scriptFlag:    flag.String("script").WithDescription("execute command lines from the file, '-' reads them from the input"),
+		recoverPanics: true,
+		stdin:         os.Stdin,
+		stdout:        os.Stdout,
+		stderr:        os.Stderr,
+	}
+	w.terminal = prompt.Terminal(w.stdin, w.stderr)
+	w.warningSink = func(warning string) {
+		fmt.Fprintln(w.stderr, "warning: "+warning)
 	}
+	return w
 }
 
 var _ Workflow = (*workflow)(nil)
 
 type workflow struct {
-	flags    []common.Flag
-	commands []common.CommandDeclaration
-	setup    func(ctx common.Runtime) error
-	cleanup  func(ctx common.Runtime, err error)
-	onError  func(ctx common.Runtime, err error)
-	declErrs []error
-	helpFlag common.Flag
+	name          string
+	description   string
+	flags         []common.Flag
+	commands      []common.CommandDeclaration
+	setup         func(ctx common.Runtime) error
+	cleanup       func(ctx common.Runtime, err error)
+	onError       func(ctx common.Runtime, err error)
+	declErrs      []error
+	helpFlag      common.Flag
+	helpPrinter   help.Printer
+	warningSink   func(warning string)
+	signals       *signalHandling
+	timeout       time.Duration
+	timeoutFlag   string
+	recoverPanics bool
+	middlewares   []common.Middleware
+	stdin         io.Reader
+	stdout        io.Writer
+	stderr        io.Writer
+	renderer      render.Renderer
+	outputFlag    common.Flag
+	prompter      prompt.Prompter
+	terminal      prompt.Prompter
+	scriptFlag    common.Flag
+	scriptPolicy  ScriptErrorPolicy
+	responseFiles bool
+	plugins       bool
+	pluginDirs    []string
+}
+
+func (w *workflow) Run(cmd []string) error {
+	return w.RunContext(stdcontext.Background(), cmd)
 }
 
-func (w *workflow) Run(cmd []string) (err error) {
+func (w *workflow) RunContext(ctx stdcontext.Context, cmd []string) (err error) {
 	// execution impossible because of invalid declarations
 	if len(w.declErrs) != 0 {
 		return common.DeclarationErrors(w.declErrs)
 	}
 
+	// arguments are read from response files before anything else
+	if w.GetDeclaredResponseFiles() {
+		if cmd, err = expandResponseFiles(cmd); err != nil {
+			return
+		}
+	}
+
 	// if no commands provided then we have nothing to execute
 	if len(cmd) == 0 {
 		return nil
 	}
 
-	var runCtx common.Runtime
-	runCtx, err = parse(w, cmd)
+	var interrupts <-chan os.Signal
+	if w.signals != nil {
+		var cancel stdcontext.CancelFunc
+		ctx, cancel = stdcontext.WithCancel(ctx)
+		defer cancel()
+		var stop func()
+		interrupts, stop = w.signals.trap(cancel)
+		defer stop()
+	}
+
+	if path, found, scriptErr := w.scriptPath(cmd); found || scriptErr != nil {
+		if scriptErr != nil {
+			return scriptErr
+		}
+		return w.runScriptFile(ctx, path)
+	}
+
+	inv, err := parse(ctx, w, cmd, session{interactive: true, prompter: w.terminal})
 	if err != nil {
 		return
 	}
 
+	if sink := w.GetDeclaredWarningSink(); sink != nil {
+		for _, warning := range inv.warnings {
+			sink(warning)
+		}
+	}
+
+	// help requested, nothing to execute
+	if inv.help {
+		return w.printHelp(inv.path)
+	}
+	runCtx := inv.runtime
+
 	defer func() {
 		// 3. if execution error happens handle it properly first
 		if err != nil && len(w.declErrs) == 0 {
 			if onError := w.GetDeclaredOnError(); onError != nil {
-				onError(runCtx, err)
+				if panicErr := w.callHook(onError, runCtx, err); panicErr != nil {
+					err = panicErr
+				}
 			}
 		}
 
 		// 4. and then run `Cleanup` if defined
 		if cleanup := w.GetDeclaredCleanup(); cleanup != nil {
-			cleanup(runCtx, err)
+			if panicErr := w.callHook(cleanup, runCtx, err); panicErr != nil {
+				err = panicErr
+			}
 		}
 	}()
 
-	// 1. execution of user-defined action starts with `Setup` action
-	if setup := w.GetDeclaredSetup(); setup != nil {
-		if err = setup(runCtx); err != nil {
-			return
+	runCtx, err = w.execute(runCtx, interrupts)
+	return
+}
+
+// execute runs `Setup` and all commands found in arguments and returns runtime for `OnError` and `Cleanup`
+// If interruption signal received it waits for completion no longer than grace period
+// Execution runs on a snapshot of `runCtx`, so if it is abandoned hooks get another snapshot not used by it
+func (w *workflow) execute(runCtx common.Runtime, interrupts <-chan os.Signal) (common.Runtime, error) {
+	run := func(runCtx common.Runtime) (err error) {
+		if w.GetDeclaredPanicRecovery() {
+			defer common.RecoverPanic(&err)
+		}
+
+		// 1. execution of user-defined action starts with `Setup` action
+		if setup := w.GetDeclaredSetup(); setup != nil {
+			if err := setup(runCtx); err != nil {
+				return err
+			}
 		}
+
+		// 2. after `Setup` starts execution of all commands found in arguments
+		return runCtx.Run()
+	}
+	if interrupts == nil {
+		return runCtx, run(runCtx)
 	}
 
-	// 2. after `Setup` starts execution of all commands found in arguments
-	err = runCtx.Run()
-	return
+	isolated := context.Snapshot(runCtx)
+	done := make(chan error, 1)
+	go func() {
+		done <- run(isolated)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			return isolated, nil
+		}
+		select {
+		case sig := <-interrupts:
+			return isolated, common.InterruptedError(sig.String())
+		default:
+			return isolated, err
+		}
+	case sig := <-interrupts:
+		gracePeriod := time.NewTimer(w.signals.gracePeriod)
+		defer gracePeriod.Stop()
+		select {
+		case <-done:
+			return isolated, common.InterruptedError(sig.String())
+		case <-gracePeriod.C:
+			return context.Snapshot(isolated), common.InterruptedError(sig.String())
+		}
+	}
 }
 
 func (w *workflow) WithSetup(action func(ctx common.Runtime) error) Workflow {
@@ -174,4 +440,222 @@ func (w *workflow) WithHelpFlag(helpFlag common.Flag) Workflow {
 
 func (w *workflow) GetDeclaredHelpFlag() common.Flag {
 	return w.helpFlag
-}
\ No newline at end of file
+}
+
+func (w *workflow) WithHelpPrinter(printer help.Printer) Workflow {
+	w.helpPrinter = printer
+	return w
+}
+
+func (w *workflow) GetDeclaredHelpPrinter() help.Printer {
+	if w.helpPrinter == nil {
+		return help.New(w.stdout)
+	}
+	return w.helpPrinter
+}
+
+func (w *workflow) WithName(name string) Workflow {
+	w.name = name
+	return w
+}
+
+func (w *workflow) GetDeclaredName() string {
+	return w.name
+}
+
+func (w *workflow) WithDescription(value string) Workflow {
+	w.description = value
+	return w
+}
+
+func (w *workflow) GetDeclaredDescription() string {
+	return w.description
+}
+
+func (w *workflow) WithWarningSink(sink func(warning string)) Workflow {
+	w.warningSink = sink
+	return w
+}
+
+func (w *workflow) GetDeclaredWarningSink() func(warning string) {
+	return w.warningSink
+}
+
+func (w *workflow) WithSignalHandling(gracePeriod time.Duration, signals <-chan os.Signal) Workflow {
+	w.signals = &signalHandling{gracePeriod: gracePeriod, source: signals, exit: os.Exit}
+	return w
+}
+
+func (w *workflow) GetDeclaredSignalHandling() (time.Duration, bool) {
+	if w.signals == nil {
+		return 0, false
+	}
+	return w.signals.gracePeriod, true
+}
+
+func (w *workflow) WithTimeout(timeout time.Duration) Workflow {
+	w.timeout = timeout
+	return w
+}
+
+func (w *workflow) GetDeclaredTimeout() time.Duration {
+	return w.timeout
+}
+
+func (w *workflow) WithTimeoutFlag(name string) Workflow {
+	w.timeoutFlag = name
+	return w
+}
+
+func (w *workflow) GetDeclaredTimeoutFlag() string {
+	return w.timeoutFlag
+}
+
+func (w *workflow) WithPanicRecovery(enabled bool) Workflow {
+	w.recoverPanics = enabled
+	return w
+}
+
+func (w *workflow) GetDeclaredPanicRecovery() bool {
+	return w.recoverPanics
+}
+
+func (w *workflow) Use(middlewares ...common.Middleware) Workflow {
+	for _, middleware := range middlewares {
+		if middleware == nil {
+			w.declErrs = append(w.declErrs, common.ActionInvalidError("middleware is 'nil': Use"))
+		}
+	}
+	w.middlewares = append(w.middlewares, middlewares...)
+	return w
+}
+
+func (w *workflow) GetDeclaredMiddlewares() []common.Middleware {
+	return w.middlewares
+}
+
+func (w *workflow) WithIO(in io.Reader, out io.Writer, errOut io.Writer) Workflow {
+	if in != nil {
+		w.stdin = in
+	}
+	if out != nil {
+		w.stdout = out
+	}
+	if errOut != nil {
+		w.stderr = errOut
+	}
+	if in != nil || errOut != nil {
+		w.terminal = prompt.Terminal(w.stdin, w.stderr)
+	}
+	return w
+}
+
+func (w *workflow) GetDeclaredIO() (io.Reader, io.Writer, io.Writer) {
+	return w.stdin, w.stdout, w.stderr
+}
+
+func (w *workflow) WithRenderer(renderer render.Renderer) Workflow {
+	w.renderer = renderer
+	return w
+}
+
+func (w *workflow) GetDeclaredRenderer() render.Renderer {
+	if w.renderer == nil {
+		w.renderer = render.New()
+	}
+	return w.renderer
+}
+
+func (w *workflow) WithOutputFlag(outputFlag common.Flag) Workflow {
+	w.outputFlag = outputFlag
+	return w
+}
+
+func (w *workflow) GetDeclaredOutputFlag() common.Flag {
+	return w.outputFlag
+}
+
+func (w *workflow) WithPrompter(prompter prompt.Prompter) Workflow {
+	w.prompter = prompter
+	return w
+}
+
+func (w *workflow) GetDeclaredPrompter() prompt.Prompter {
+	return w.prompter
+}
+
+func (w *workflow) WithScriptFlag(scriptFlag common.Flag) Workflow {
+	w.scriptFlag = scriptFlag
+	return w
+}
+
+func (w *workflow) GetDeclaredScriptFlag() common.Flag {
+	return w.scriptFlag
+}
+
+func (w *workflow) WithScriptErrorPolicy(policy ScriptErrorPolicy) Workflow {
+	w.scriptPolicy = policy
+	return w
+}
+
+func (w *workflow) GetDeclaredScriptErrorPolicy() ScriptErrorPolicy {
+	return w.scriptPolicy
+}
+
+func (w *workflow) WithResponseFiles(enabled bool) Workflow {
+	w.responseFiles = enabled
+	return w
+}
+
+func (w *workflow) GetDeclaredResponseFiles() bool {
+	return w.responseFiles
+}
+
+func (w *workflow) WithPlugins(enabled bool, dirs ...string) Workflow {
+	w.plugins = enabled
+	w.pluginDirs = dirs
+	return w
+}
+
+func (w *workflow) GetDeclaredPlugins() ([]string, bool) {
+	return w.pluginDirs, w.plugins
+}
+
+// call executes `action` and returns error caused by `ErrorPanic` if it panicked and panic recovery is enabled
+func (w *workflow) call(action func(ctx common.Runtime) error, runCtx common.Runtime) (err error) {
+	if w.GetDeclaredPanicRecovery() {
+		defer common.RecoverPanic(&err)
+	}
+	return action(runCtx)
+}
+
+// callHook executes `hook` and returns error only if it panicked and panic recovery is enabled
+func (w *workflow) callHook(hook func(ctx common.Runtime, err error), runCtx common.Runtime, err error) (panicErr error) {
+	if w.GetDeclaredPanicRecovery() {
+		defer common.RecoverPanic(&panicErr)
+	}
+	hook(runCtx, err)
+	return nil
+}
+
+// printHelp prints help information for the command identified by `path` or for the whole application if `path` is empty
+func (w *workflow) printHelp(path []common.CommandDeclaration) error {
+	printer := w.GetDeclaredHelpPrinter()
+	if printer == nil {
+		return nil
+	}
+
+	var plugins []common.CommandDeclaration
+	if len(path) == 0 {
+		plugins = discoverPlugins(w)
+	}
+
+	return printer.Print(help.Usage{
+		Name:        w.GetDeclaredName(),
+		Description: w.GetDeclaredDescription(),
+		GlobalFlags: help.GlobalFlags(w.GetDeclaredGlobalFlags(), w.GetDeclaredOutputFlag(), w.GetDeclaredScriptFlag(), w.GetDeclaredHelpFlag()),
+		Commands:    w.GetDeclaredCommands(),
+		Plugins:     plugins,
+		Path:        path,
+	})
+}
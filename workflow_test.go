@@ -1,42 +1,52 @@
 package stalk
 
 import (
+	"bytes"
+	stdcontext "context"
 	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
 	"testing"
 
 	"errors"
 	"github.com/pavelmemory/stalk/command"
 	"github.com/pavelmemory/stalk/common"
 	"github.com/pavelmemory/stalk/flag"
+	"github.com/pavelmemory/stalk/help"
 )
 
 func TestWorkflow_Run(t *testing.T) {
 	createCmd := command.New("create").
 		WithFlags(flag.String("name").Required(true).WithShortcut('n')).
 		WithBefore(func(ctx common.Runtime) error {
-			fmt.Println("before create command")
+			fmt.Fprintln(ctx.Stdout(), "before create command")
 			return nil
 		}).
 		WithAction(func(ctx common.Runtime) error {
-			fmt.Println("create command")
-			fmt.Println("ctx.HasGlobalFlag(\"verbose\")", ctx.HasGlobalFlag("verbose"))
-			fmt.Println("ctx.StringFlag(\"name\")", ctx.StringFlag("name"))
-			fmt.Println("ctx.HasFlag(\"name\")", ctx.HasFlag("name"))
-			fmt.Println("ctx.GetArgs()", ctx.GetArgs())
-			fmt.Println("ctx.GlobalFlags()", ctx.GlobalFlags())
-			fmt.Println("ctx.Flags()", ctx.Flags())
+			out := ctx.Stdout()
+			globalFlags, flags := ctx.GlobalFlags(), ctx.Flags()
+			sort.Strings(globalFlags)
+			sort.Strings(flags)
+			fmt.Fprintln(out, "create command")
+			fmt.Fprintln(out, "ctx.HasGlobalFlag(\"verbose\")", ctx.HasGlobalFlag("verbose"))
+			fmt.Fprintln(out, "ctx.StringFlag(\"name\")", ctx.StringFlag("name"))
+			fmt.Fprintln(out, "ctx.HasFlag(\"name\")", ctx.HasFlag("name"))
+			fmt.Fprintln(out, "ctx.GetArgs()", ctx.GetArgs())
+			fmt.Fprintln(out, "ctx.GlobalFlags()", globalFlags)
+			fmt.Fprintln(out, "ctx.Flags()", flags)
 			v, f := ctx.Get("1")
-			fmt.Println("ctx.Get(\"1\")", v, f)
+			fmt.Fprintln(out, "ctx.Get(\"1\")", v, f)
 			return nil
 		})
 
 	showCmd := command.New("show").
 		WithFlags(flag.String("name").Required(true).WithShortcut('n')).
 		WithAction(func(ctx common.Runtime) error {
-			fmt.Println("show command")
-			fmt.Println(ctx.StringGlobalFlag("example"))
-			fmt.Println(ctx.StringGlobalFlag("help"))
-			fmt.Println(ctx.StringFlag("name"))
+			fmt.Fprintln(ctx.Stdout(), "show command")
+			fmt.Fprintln(ctx.Stdout(), ctx.StringGlobalFlag("example"))
+			fmt.Fprintln(ctx.Stdout(), ctx.StringGlobalFlag("help"))
+			fmt.Fprintln(ctx.Stdout(), ctx.StringFlag("name"))
 			return nil
 		})
 
@@ -47,7 +57,9 @@ func TestWorkflow_Run(t *testing.T) {
 			return nil
 		})
 
+	out := &bytes.Buffer{}
 	app := New().
+		WithIO(nil, out, nil).
 		WithCommands(aws).
 		WithGlobalFlags(
 			flag.String("example").WithShortcut('e'),
@@ -57,6 +69,58 @@ func TestWorkflow_Run(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	expected := `before create command
+create command
+ctx.HasGlobalFlag("verbose") true
+ctx.StringFlag("name") tattoo
+ctx.HasFlag("name") true
+ctx.GetArgs() [valhalla and dumb]
+ctx.GlobalFlags() [example output verbose]
+ctx.Flags() [name]
+ctx.Get("1") something true
+`
+	if actual := out.String(); expected != actual {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+}
+
+func TestWorkflow_WithIO(t *testing.T) {
+	in := strings.NewReader("input line")
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	app := New().
+		WithName("app").
+		WithIO(in, out, errOut).
+		WithCommands(
+			command.New("echo").
+				WithFlags(flag.Signal("old").Deprecated("", "")).
+				WithAction(func(ctx common.Runtime) error {
+					line, err := ioutil.ReadAll(ctx.Stdin())
+					if err != nil {
+						return err
+					}
+					fmt.Fprintln(ctx.Stdout(), string(line))
+					fmt.Fprintln(ctx.Stderr(), "done")
+					return nil
+				}))
+
+	if err := app.Run([]string{"echo", "--old"}); err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "input line\n", out.String(); expected != actual {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+	if expected, actual := "warning: flag '--old' is deprecated\ndone\n", errOut.String(); expected != actual {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", actual)
+	}
+
+	out.Reset()
+	if err := app.Run([]string{"--help"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Usage: app") {
+		t.Error("help expected in the output stream, got:", out.String())
+	}
 }
 
 func TestWorkflow_CurrentCommand(t *testing.T) {
@@ -110,3 +174,228 @@ func TestWorkflow_CurrentCommand(t *testing.T) {
 		t.Error("not all checks triggered")
 	}
 }
+
+func TestWorkflow_Run_Help(t *testing.T) {
+	executed := false
+	create := command.New("create").
+		WithFlags(flag.String("name").Required(true)).
+		WithAction(func(ctx common.Runtime) error {
+			executed = true
+			return nil
+		})
+
+	for _, args := range [][]string{
+		{"--help"},
+		{"aws", "-h"},
+		{"aws", "create", "--help"},
+	} {
+		buf := &bytes.Buffer{}
+		err := New().
+			WithName("app").
+			WithCommands(command.New("aws").WithSubCommands(create)).
+			WithHelpPrinter(help.New(buf).WithWidth(80)).
+			Run(args)
+		if err != nil {
+			t.Fatal(args, err)
+		}
+		if !strings.HasPrefix(buf.String(), "Usage: app") {
+			t.Error(args, "usage expected, got:\n", buf.String())
+		}
+	}
+	if executed {
+		t.Error("action must not be executed when help requested")
+	}
+}
+
+func TestValidateExamples(t *testing.T) {
+	create := command.New("create").
+		WithFlags(flag.String("name").Required(true).WithShortcut('n')).
+		WithExample("valid", "aws create --name 'quoted name'").
+		WithExample("valid shortcut", "aws create -n test arg").
+		WithExample("renamed flag", "aws create --title test").
+		WithExample("another command", "aws list").
+		WithExample("unterminated quote", "aws create --name 'test").
+		WithAction(emptyAction)
+	list := command.New("list").WithAction(emptyAction)
+	wf := New().WithCommands(command.New("aws").WithSubCommands(create, list))
+
+	errs := ValidateExamples(wf)
+	if len(errs) != 3 {
+		t.Fatal("3 errors expected, got:", errs)
+	}
+	for _, err := range errs {
+		if cErr, ok := err.(common.Error); !ok || cErr.Cause != common.ErrorExampleInvalid {
+			t.Error("unexpected error:", err)
+		}
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	for index, scenario := range []struct {
+		line     string
+		expected []string
+	}{
+		/*1*/ {"aws create", []string{"aws", "create"}},
+		/*2*/ {"  spaces   around  ", []string{"spaces", "around"}},
+		/*3*/ {`single 'quoted "value"'`, []string{"single", `quoted "value"`}},
+		/*4*/ {`double "quoted \"value\" \n"`, []string{"double", `quoted "value" \n`}},
+		/*5*/ {`escaped\ space`, []string{"escaped space"}},
+		/*6*/ {`empty '' ""`, []string{"empty", "", ""}},
+		/*7*/ {`joined'single'"double"`, []string{"joinedsingledouble"}},
+	} {
+		actual, err := splitCommandLine(scenario.line)
+		if err != nil {
+			t.Fatal(index+1, err)
+		}
+		if strings.Join(actual, "|") != strings.Join(scenario.expected, "|") || len(actual) != len(scenario.expected) {
+			t.Error("index:", index+1, "\nexpected:\n", scenario.expected, "\nactual:\n", actual)
+		}
+	}
+
+	if _, err := splitCommandLine(`"unterminated`); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestWorkflow_Run_Deprecated(t *testing.T) {
+	var warnings []string
+	var name string
+	var verbose bool
+	create := command.New("create").
+		WithFlags(
+			flag.String("name").Required(true),
+			flag.String("title").Deprecated("titles are names now", "name")).
+		WithAction(func(ctx common.Runtime) error {
+			name = ctx.StringFlag("name")
+			verbose = ctx.HasGlobalFlag("verbose")
+			return nil
+		})
+	wf := New().
+		WithGlobalFlags(
+			flag.Signal("verbose"),
+			flag.Signal("loud").Deprecated("", "verbose").Hidden()).
+		WithCommands(
+			create,
+			command.New("make").Deprecated("", "create").WithSubCommands(create)).
+		WithWarningSink(func(warning string) {
+			warnings = append(warnings, warning)
+		})
+
+	if err := wf.Run([]string{"--loud", "make", "create", "--title", "forwarded"}); err != nil {
+		t.Fatal(err)
+	}
+	if name != "forwarded" || !verbose {
+		t.Error("values of deprecated flags expected to be forwarded, got:", name, verbose)
+	}
+	expected := []string{
+		"flag '--loud' is deprecated, use 'verbose' instead",
+		"command 'make' is deprecated, use 'create' instead",
+		"flag '--title' is deprecated: titles are names now, use 'name' instead",
+	}
+	if strings.Join(expected, "\n") != strings.Join(warnings, "\n") {
+		t.Error("\nexpected:\n", expected, "\nactual:\n", warnings)
+	}
+
+	warnings = nil
+	if err := wf.Run([]string{"create", "--title", "ignored", "--name", "explicit"}); err != nil {
+		t.Fatal(err)
+	}
+	if name != "explicit" {
+		t.Error("explicitly provided replacement expected to take precedence, got:", name)
+	}
+	if len(warnings) != 1 {
+		t.Error("one warning expected, got:", warnings)
+	}
+}
+
+func TestWorkflow_RunContext(t *testing.T) {
+	type key struct{}
+	ctx, cancel := stdcontext.WithCancel(stdcontext.WithValue(stdcontext.Background(), key{}, "value"))
+	cancel()
+
+	observed := 0
+	check := func(runtime common.Runtime) {
+		if runtime.Context().Value(key{}) != "value" || runtime.Context().Err() == nil {
+			t.Error("context provided to RunContext expected")
+		}
+		observed++
+	}
+	err := New().
+		WithSetup(func(ctx common.Runtime) error { check(ctx); return nil }).
+		WithCleanup(func(ctx common.Runtime, err error) { check(ctx) }).
+		WithCommands(command.New("cmd").
+			WithBefore(func(ctx common.Runtime) error { check(ctx); return nil }).
+			WithAction(func(ctx common.Runtime) error {
+				check(ctx)
+				return ctx.Context().Err()
+			}).
+			WithOnError(func(ctx common.Runtime, err error) { check(ctx) }).
+			WithAfter(func(ctx common.Runtime, err error) { check(ctx) })).
+		RunContext(ctx, []string{"cmd"})
+	if err != stdcontext.Canceled {
+		t.Error("cancellation error expected, got:", err)
+	}
+	if observed != 6 {
+		t.Error("all actions expected to observe context, observed:", observed)
+	}
+}
+
+func TestWorkflow_Run_LifecycleOrder(t *testing.T) {
+	childErr := errors.New("child failed")
+	for index, scenario := range []struct {
+		childErr error
+		expected []string
+	}{
+		/*1*/ {nil, []string{
+			"Setup",
+			"parent Before", "parent Action",
+			"child Before", "child Action",
+			"child After <nil>",
+			"parent After <nil>",
+			"Cleanup <nil>",
+		}},
+		/*2*/ {childErr, []string{
+			"Setup",
+			"parent Before", "parent Action",
+			"child Before", "child Action",
+			"child OnError child failed", "child After child failed",
+			"parent OnError child failed", "parent After child failed",
+			"OnError child failed", "Cleanup child failed",
+		}},
+	} {
+		var actual []string
+		action := func(name string, err error) func(common.Runtime) error {
+			return func(ctx common.Runtime) error {
+				actual = append(actual, name)
+				return err
+			}
+		}
+		hook := func(name string) func(common.Runtime, error) {
+			return func(ctx common.Runtime, err error) {
+				actual = append(actual, fmt.Sprint(name, " ", err))
+			}
+		}
+
+		err := New().
+			WithSetup(action("Setup", nil)).
+			WithOnError(hook("OnError")).
+			WithCleanup(hook("Cleanup")).
+			WithCommands(command.New("parent").
+				WithBefore(action("parent Before", nil)).
+				WithAction(action("parent Action", nil)).
+				WithOnError(hook("parent OnError")).
+				WithAfter(hook("parent After")).
+				WithSubCommands(command.New("child").
+					WithBefore(action("child Before", nil)).
+					WithAction(action("child Action", scenario.childErr)).
+					WithOnError(hook("child OnError")).
+					WithAfter(hook("child After")))).
+			Run([]string{"parent", "child"})
+		if err != scenario.childErr {
+			t.Error("index:", index+1, "unexpected error:", err)
+		}
+		if strings.Join(scenario.expected, "\n") != strings.Join(actual, "\n") {
+			t.Error("index:", index+1, "\nexpected:\n", strings.Join(scenario.expected, "\n"), "\nactual:\n", strings.Join(actual, "\n"))
+		}
+	}
+}